@@ -135,6 +135,95 @@ func (a *Assign) String() string {
 	return sb.String()
 }
 
+// CompoundAssign is used for compound assignments and increments of a
+// variable or a property
+// x += value, x++, --x
+type CompoundAssign struct {
+	Expr
+	Target   Expr // *Variable or *Get
+	Operator token.Token
+	Value    Expr
+	Postfix  bool
+}
+
+// String pretty prints the compound assignment
+func (c *CompoundAssign) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	switch {
+	case c.Operator.Type == token.PLUSPLUS || c.Operator.Type == token.MINUSMINUS:
+		if c.Postfix {
+			sb.WriteString(c.Target.String())
+			sb.WriteString(" ")
+			sb.WriteString(c.Operator.Lexeme)
+		} else {
+			sb.WriteString(c.Operator.Lexeme)
+			sb.WriteString(" ")
+			sb.WriteString(c.Target.String())
+		}
+	default:
+		sb.WriteString(c.Operator.Lexeme)
+		sb.WriteString(" ")
+		sb.WriteString(c.Target.String())
+		sb.WriteString(" ")
+		sb.WriteString(c.Value.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// ListLiteral creates a new list from its elements
+// [a, b, c]
+type ListLiteral struct {
+	Expr
+	Bracket  token.Token
+	Elements []Expr
+}
+
+// String pretty prints the list literal
+func (l *ListLiteral) String() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, element := range l.Elements {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(element.String())
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// DestructureAssign assigns the elements of a list to variables or
+// properties
+// [a, b] = <value>
+type DestructureAssign struct {
+	Expr
+	Bracket token.Token
+	Targets []Expr // *Variable or *Get
+	Value   Expr
+}
+
+// String pretty prints the destructuring assignment
+func (d *DestructureAssign) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString("=")
+	sb.WriteString(" ")
+	sb.WriteString("[")
+	for i, target := range d.Targets {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(target.String())
+	}
+	sb.WriteString("]")
+	sb.WriteString(" ")
+	sb.WriteString(d.Value.String())
+	sb.WriteString(")")
+	return sb.String()
+}
+
 // Variable access expression
 // print x
 type Variable struct {
@@ -212,18 +301,24 @@ func (p *Print) String() string {
 
 // Var is the variable declaration statement
 // var <name> = <initializer>
+// const <name> = <initializer>
 type Var struct {
 	Stmt
 	Name        token.Token
 	Initializer Expr
 	EnvIndex    int
+	Const       bool
 }
 
 // String pretty prints the var declaration
 func (v *Var) String() string {
 	var sb strings.Builder
 	sb.WriteString("(")
-	sb.WriteString("var")
+	if v.Const {
+		sb.WriteString("const")
+	} else {
+		sb.WriteString("var")
+	}
 	sb.WriteString(" ")
 	sb.WriteString(v.Name.Lexeme)
 	sb.WriteString(" ")
@@ -236,6 +331,58 @@ func (v *Var) String() string {
 	return sb.String()
 }
 
+// Binding is a single variable declared by a destructuring declaration
+type Binding struct {
+	Stmt
+	Name     token.Token
+	EnvIndex int
+}
+
+// String pretty prints the binding
+func (b *Binding) String() string {
+	return b.Name.Lexeme
+}
+
+// Destructure is the destructuring declaration statement. Pattern is '['
+// for the elements of a list and '{' for the properties of an instance.
+// var [a, b] = <initializer>
+// var {x, y} = <initializer>
+type Destructure struct {
+	Stmt
+	Pattern     token.Token
+	Bindings    []*Binding
+	Initializer Expr
+	Const       bool
+}
+
+// String pretty prints the destructuring declaration
+func (d *Destructure) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	if d.Const {
+		sb.WriteString("const")
+	} else {
+		sb.WriteString("var")
+	}
+	sb.WriteString(" ")
+	sb.WriteString(d.Pattern.Lexeme)
+	for i, binding := range d.Bindings {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(binding.String())
+	}
+	if d.Pattern.Type == token.LEFTBRACKET {
+		sb.WriteString("]")
+	} else {
+		sb.WriteString("}")
+	}
+	sb.WriteString(" ")
+	sb.WriteString(d.Initializer.String())
+	sb.WriteString(")")
+	return sb.String()
+}
+
 // If is the classic if statement
 type If struct {
 	Stmt
@@ -262,6 +409,7 @@ func (i *If) String() string {
 // For ...
 type For struct {
 	Stmt
+	Label       token.Token // empty for unlabeled loops
 	Initializer Expr
 	Condition   Expr
 	Increment   Expr
@@ -288,12 +436,43 @@ func (f *For) String() string {
 	sb.WriteString(" ")
 	sb.WriteString(f.Statement.String())
 	sb.WriteString(")")
-	return sb.String()
+	return labeled(f.Label, sb.String())
+}
+
+// ForIn iterates over the elements of a list, the keys of a map, the
+// characters of a string, a range or an iterator object
+type ForIn struct {
+	Stmt
+	Label     token.Token // empty for unlabeled loops
+	Keyword   token.Token // 'in', used to report runtime errors
+	Name      token.Token
+	EnvIndex  int
+	EnvSize   int
+	Iterable  Expr
+	Statement Stmt
+}
+
+// String pretty prints the for-in statement
+func (f *ForIn) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString("for")
+	sb.WriteString(" ")
+	sb.WriteString(f.Name.Lexeme)
+	sb.WriteString(" ")
+	sb.WriteString("in")
+	sb.WriteString(" ")
+	sb.WriteString(f.Iterable.String())
+	sb.WriteString(" ")
+	sb.WriteString(f.Statement.String())
+	sb.WriteString(")")
+	return labeled(f.Label, sb.String())
 }
 
 // While is the classic while statement
 type While struct {
 	Stmt
+	Label     token.Token // empty for unlabeled loops
 	Condition Expr
 	Statement Stmt
 }
@@ -308,7 +487,36 @@ func (w *While) String() string {
 	sb.WriteString(" ")
 	sb.WriteString(w.Statement.String())
 	sb.WriteString(")")
-	return sb.String()
+	return labeled(w.Label, sb.String())
+}
+
+// DoWhile is a loop that checks its condition after each iteration
+type DoWhile struct {
+	Stmt
+	Label     token.Token // empty for unlabeled loops
+	Statement Stmt
+	Condition Expr
+}
+
+// String pretty prints the do-while statement
+func (d *DoWhile) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString("do")
+	sb.WriteString(" ")
+	sb.WriteString(d.Statement.String())
+	sb.WriteString(" ")
+	sb.WriteString(d.Condition.String())
+	sb.WriteString(")")
+	return labeled(d.Label, sb.String())
+}
+
+// labeled prefixes the pretty printed loop with its label
+func labeled(label token.Token, loop string) string {
+	if label.Lexeme == "" {
+		return loop
+	}
+	return label.Lexeme + ": " + loop
 }
 
 // Logical is used for the "or" and "and" operators.
@@ -355,14 +563,45 @@ func (c *Call) String() string {
 	return sb.String()
 }
 
-// Function is the function definition node
+// Spread expands a list into separate call arguments
+// f(...xs)
+type Spread struct {
+	Expr
+	Ellipsis   token.Token
+	Expression Expr
+}
+
+// String pretty prints the spread argument
+func (s *Spread) String() string {
+	return "..." + s.Expression.String()
+}
+
+// NamedArgument passes an argument by parameter name
+// f(name: value)
+type NamedArgument struct {
+	Expr
+	Name  token.Token
+	Value Expr
+}
+
+// String pretty prints the named argument
+func (n *NamedArgument) String() string {
+	return n.Name.Lexeme + ":" + n.Value.String()
+}
+
+// Function is the function definition node. Defaults has an entry for
+// each parameter, nil for the required ones. If Variadic is true the last
+// parameter collects the remaining arguments in a list.
 type Function struct {
 	Name          token.Token
 	Params        []token.Token
+	Defaults      []Expr
+	Variadic      bool
 	Body          []Stmt
 	EnvSize       int
 	EnvIndex      int
 	IsClassMethod bool
+	IsGenerator   bool // the body contains a yield statement
 }
 
 // IsProperty is true if this function is a class property
@@ -379,8 +618,15 @@ func (f *Function) String() string {
 	sb.WriteString(f.Name.Lexeme)
 	sb.WriteString(" ")
 	sb.WriteString("(")
-	for _, p := range f.Params {
+	for i, p := range f.Params {
+		if f.Variadic && i == len(f.Params)-1 {
+			sb.WriteString("...")
+		}
 		sb.WriteString(p.Lexeme)
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			sb.WriteString("=")
+			sb.WriteString(f.Defaults[i].String())
+		}
 		sb.WriteString(" ")
 	}
 	sb.WriteString(")")
@@ -414,10 +660,196 @@ func (r *Return) String() string {
 	return sb.String()
 }
 
+// Match runs the first case whose pattern matches the value
+// match (<value>) { case <patterns> [if <guard>] => <body> ... }
+type Match struct {
+	Stmt
+	Keyword token.Token
+	Value   Expr
+	Cases   []*MatchCase
+}
+
+// String pretty prints the match statement
+func (m *Match) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString("match")
+	sb.WriteString(" ")
+	sb.WriteString(m.Value.String())
+	for _, c := range m.Cases {
+		sb.WriteString(" ")
+		sb.WriteString(c.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// MatchCase is a single case of a match statement. The case matches if any
+// of its alternative patterns does. Its bindings live in an environment of
+// their own, shared by the guard and the body.
+type MatchCase struct {
+	Stmt
+	Keyword  token.Token
+	Patterns []Pattern
+	Guard    Expr
+	Body     Stmt
+	EnvSize  int
+}
+
+// String pretty prints the case
+func (c *MatchCase) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString("case")
+	for _, pattern := range c.Patterns {
+		sb.WriteString(" ")
+		sb.WriteString(pattern.String())
+	}
+	if c.Guard != nil {
+		sb.WriteString(" if ")
+		sb.WriteString(c.Guard.String())
+	}
+	sb.WriteString(" ")
+	sb.WriteString(c.Body.String())
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// Pattern is the root class of match patterns
+type Pattern interface {
+	Node
+}
+
+// LiteralPattern matches values equal to a literal
+type LiteralPattern struct {
+	Pattern
+	Value Expr
+}
+
+// String pretty prints the literal pattern
+func (l *LiteralPattern) String() string {
+	return l.Value.String()
+}
+
+// WildcardPattern (_) matches any value
+type WildcardPattern struct {
+	Pattern
+	Token token.Token
+}
+
+// String pretty prints the wildcard pattern
+func (w *WildcardPattern) String() string {
+	return "_"
+}
+
+// BindingPattern matches any value and binds it to a variable
+type BindingPattern struct {
+	Pattern
+	Name     token.Token
+	EnvIndex int
+}
+
+// String pretty prints the binding pattern
+func (b *BindingPattern) String() string {
+	return b.Name.Lexeme
+}
+
+// ListPattern matches lists element by element. Without a Rest pattern,
+// the lengths must be equal; otherwise Rest is matched against a list of the
+// remaining elements.
+type ListPattern struct {
+	Pattern
+	Bracket  token.Token
+	Elements []Pattern
+	Rest     Pattern
+}
+
+// String pretty prints the list pattern
+func (l *ListPattern) String() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, element := range l.Elements {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(element.String())
+	}
+	if l.Rest != nil {
+		if len(l.Elements) > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString("...")
+		sb.WriteString(l.Rest.String())
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// ClassPattern matches the instances of a class (or of its subclasses)
+// whose fields match the field patterns
+type ClassPattern struct {
+	Pattern
+	Class  *Variable
+	Fields []*FieldPattern
+}
+
+// String pretty prints the class pattern
+func (c *ClassPattern) String() string {
+	var sb strings.Builder
+	sb.WriteString(c.Class.Name.Lexeme)
+	sb.WriteString("(")
+	for i, field := range c.Fields {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(field.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// FieldPattern matches a field of an instance. 'x' is short for 'x: x'.
+type FieldPattern struct {
+	Pattern
+	Name  token.Token
+	Value Pattern
+}
+
+// String pretty prints the field pattern
+func (f *FieldPattern) String() string {
+	if binding, ok := f.Value.(*BindingPattern); ok && binding.Name.Lexeme == f.Name.Lexeme {
+		return f.Name.Lexeme
+	}
+	return f.Name.Lexeme + ":" + f.Value.String()
+}
+
+// Yield suspends a generator, producing a value
+type Yield struct {
+	Stmt
+	Keyword  token.Token
+	Value    Expr
+	EnvIndex int // the slot of the running generator
+	EnvDepth int
+}
+
+// String pretty prints the yield statement
+func (y *Yield) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString("yield")
+	if y.Value != nil {
+		sb.WriteString(" ")
+		sb.WriteString(y.Value.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
 // Break is used to return from a function
 type Break struct {
 	Stmt
 	Token token.Token
+	Label token.Token // the target loop; empty for the innermost one
 }
 
 // String pretty prints the function
@@ -425,6 +857,10 @@ func (b *Break) String() string {
 	var sb strings.Builder
 	sb.WriteString("(")
 	sb.WriteString("break")
+	if b.Label.Lexeme != "" {
+		sb.WriteString(" ")
+		sb.WriteString(b.Label.Lexeme)
+	}
 	sb.WriteString(")")
 	return sb.String()
 }
@@ -433,6 +869,7 @@ func (b *Break) String() string {
 type Continue struct {
 	Stmt
 	Token token.Token
+	Label token.Token // the target loop; empty for the innermost one
 }
 
 // String pretty prints the function
@@ -440,6 +877,10 @@ func (c *Continue) String() string {
 	var sb strings.Builder
 	sb.WriteString("(")
 	sb.WriteString("continue")
+	if c.Label.Lexeme != "" {
+		sb.WriteString(" ")
+		sb.WriteString(c.Label.Lexeme)
+	}
 	sb.WriteString(")")
 	return sb.String()
 }
@@ -480,13 +921,18 @@ type Get struct {
 	Expr
 	Name       token.Token
 	Expression Expr
+	Optional   bool // a?.b
 }
 
 // String pretty prints the class
 func (g *Get) String() string {
 	var sb strings.Builder
 	sb.WriteString("(")
-	sb.WriteString(".")
+	if g.Optional {
+		sb.WriteString("?.")
+	} else {
+		sb.WriteString(".")
+	}
 	sb.WriteString(" ")
 	sb.WriteString(g.Expression.String())
 	sb.WriteString(" ")
@@ -495,6 +941,18 @@ func (g *Get) String() string {
 	return sb.String()
 }
 
+// OptionalChain is a chain of property accesses and calls containing '?.'.
+// The whole chain evaluates to nil as soon as an optional access finds nil.
+type OptionalChain struct {
+	Expr
+	Expression Expr
+}
+
+// String pretty prints the chain
+func (o *OptionalChain) String() string {
+	return o.Expression.String()
+}
+
 // Set is used for writing to a property
 type Set struct {
 	Expr
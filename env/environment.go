@@ -13,6 +13,7 @@ var needsInitialization = &uninitialized{}
 // Environment associates variables to values
 type Environment struct {
 	values        map[string]interface{}
+	constants     map[string]bool
 	enclosing     *Environment
 	indexedValues []interface{}
 }
@@ -24,7 +25,7 @@ func New(env *Environment) *Environment {
 
 // NewSized creates a new environment
 func NewSized(env *Environment, size int) *Environment {
-	return &Environment{values: make(map[string]interface{}), enclosing: env, indexedValues: make([]interface{}, size)}
+	return &Environment{values: make(map[string]interface{}), constants: make(map[string]bool), enclosing: env, indexedValues: make([]interface{}, size)}
 }
 
 // NewGlobal creates a new global environment
@@ -41,6 +42,26 @@ func (e *Environment) Define(name string, value interface{}, index int) {
 	}
 }
 
+// DefineConstant binds a name to a value that cannot be reassigned. Only
+// named variables are checked at runtime; the resolver protects the
+// indexed ones.
+func (e *Environment) DefineConstant(name string, value interface{}, index int) {
+	e.Define(name, value, index)
+	if index == -1 {
+		e.constants[name] = true
+	}
+}
+
+// CheckRedeclaration reports an error if name is a constant of this
+// environment. The resolver rejects such declarations within a program, but
+// the REPL resolves every input separately.
+func (e *Environment) CheckRedeclaration(name token.Token, index int) error {
+	if index == -1 && e.constants[name.Lexeme] {
+		return runtimeerror.Make(name, fmt.Sprintf("Cannot redeclare constant '%s'.", name.Lexeme))
+	}
+	return nil
+}
+
 // DefineUnitialized creates a new variable. That variable must be initialized before
 // used
 func (e *Environment) DefineUnitialized(name string, index int) {
@@ -87,6 +108,9 @@ func (e *Environment) Ancestor(distance int) *Environment {
 func (e *Environment) Assign(name token.Token, index int, value interface{}) error {
 	if index == -1 {
 		if _, prs := e.values[name.Lexeme]; prs {
+			if e.constants[name.Lexeme] {
+				return runtimeerror.Make(name, fmt.Sprintf("Cannot assign to constant '%s'.", name.Lexeme))
+			}
 			e.values[name.Lexeme] = value
 			return nil
 		}
@@ -15,6 +15,7 @@ import (
 	"github.com/jfourkiotis/golox/semanticerror"
 	"io/ioutil"
 	"os"
+	"sort"
 )
 
 func check(err error) {
@@ -23,10 +24,18 @@ func check(err error) {
 	}
 }
 
-func runFile(file string) {
+var parserOptions parser.Options
+
+func runFile(file string, opts interpreter.Options) {
 	dat, err := ioutil.ReadFile(file)
 	check(err)
-	run(string(dat), interpreter.GlobalEnv)
+	opts.ScriptName = file
+	interpreter.SetOptions(opts)
+	err = run(string(dat), interpreter.GlobalEnv)
+	interpreter.CloseGenerators()
+	if exit, ok := err.(interpreter.ExitError); ok {
+		os.Exit(exit.Code)
+	}
 	if parseerror.HadError {
 		os.Exit(65)
 	} else if runtimeerror.HadError || semanticerror.HadError {
@@ -34,37 +43,56 @@ func runFile(file string) {
 	}
 }
 
-func runPrompt() {
+func runPrompt(opts interpreter.Options) {
 	reader := bufio.NewReader(os.Stdin)
+	opts.Reader = reader // scripts share the prompt's input buffer
+	interpreter.SetOptions(opts)
 	env := interpreter.GlobalEnv
 	for {
 		fmt.Print("> ")
 		dat, err := reader.ReadBytes('\n') // there is also ReadString
 		check(err)
-		run(string(dat), env)
+		if exit, ok := run(string(dat), env).(interpreter.ExitError); ok {
+			os.Exit(exit.Code)
+		}
 		parseerror.HadError = false
 		runtimeerror.HadError = false
 		semanticerror.HadError = false
 	}
 }
 
-func run(src string, env *env.Environment) {
+// run returns the error of interpreter.Interpret, if the program was executed
+func run(src string, env *env.Environment) error {
 	scanner := scanner.New(src)
 	tokens := scanner.ScanTokens()
-	parser := parser.New(tokens)
+	parser := parser.NewWithOptions(tokens, parserOptions)
 	statements := parser.Parse()
 	if parseerror.HadError {
-		return
+		return nil
 	}
 	resolution, err := semantic.Resolve(statements)
 	if err != nil || semanticerror.HadError {
 		semanticerror.Print(err.Error())
-		return
-	} else if len(resolution.Unused) != 0 {
+		return nil
+	}
+	// the warnings are printed in source order, not in map order
+	unreachable := make([]int, 0, len(resolution.Unreachable))
+	for stmt := range resolution.Unreachable {
+		if n, ok := stmt.(*ast.MatchCase); ok {
+			unreachable = append(unreachable, n.Keyword.Line)
+		}
+	}
+	sort.Ints(unreachable)
+	for _, line := range unreachable {
+		fmt.Fprintf(os.Stdout, "Warning: unreachable case [Line: %d]\n", line)
+	}
+	if len(resolution.Unused) != 0 {
 		for stmt := range resolution.Unused {
 			switch n := stmt.(type) {
 			case *ast.Var:
 				fmt.Fprintf(os.Stdout, "Unused variable %q [Line: %d]\n", n.Name.Lexeme, n.Name.Line)
+			case *ast.Binding:
+				fmt.Fprintf(os.Stdout, "Unused variable %q [Line: %d]\n", n.Name.Lexeme, n.Name.Line)
 			case *ast.Function:
 				fmt.Fprintf(os.Stdout, "Unused function %q [Line: %d]\n", n.Name.Lexeme, n.Name.Line)
 			case *ast.Class:
@@ -74,22 +102,25 @@ func run(src string, env *env.Environment) {
 			}
 		}
 		err = semanticerror.Make(fmt.Sprintf("%d unused local variables/functions found", len(resolution.Unused)))
-		return
+		return nil
 	}
-	interpreter.Interpret(statements, env, resolution)
+	return interpreter.Interpret(statements, env, resolution)
 }
 
 func main() {
 	flag.String("file", "", "the script file to execute")
+	fileRoot := flag.String("fileroot", "", "the directory scripts may access through the io natives (default: no file access)")
+	maxArguments := flag.Int("maxargs", parser.DefaultMaxArguments, "the maximum number of function parameters and call arguments")
 	flag.Parse()
 
+	parserOptions = parser.Options{MaxArguments: *maxArguments}
+	opts := interpreter.Options{FileRoot: *fileRoot}
+
 	args := flag.Args()
-	if len(args) > 1 {
-		fmt.Println("Usage: ./golox [script]")
-		os.Exit(64)
-	} else if len(args) == 1 {
-		runFile(args[0])
+	if len(args) >= 1 {
+		opts.Args = args[1:]
+		runFile(args[0], opts)
 	} else {
-		runPrompt()
+		runPrompt(opts)
 	}
 }
@@ -0,0 +1,341 @@
+package interpreter
+
+import (
+	"fmt"
+	"github.com/jfourkiotis/golox/runtimeerror"
+	"github.com/jfourkiotis/golox/token"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Besides int64 and float64, Lox has arbitrary-precision integers (*big.Int,
+// written 123n) and exact decimals (*big.Rat, written 1.25d). A decimal
+// always has a finite decimal expansion: results that would not, such as
+// 1d / 3d, are rounded to decimalPrecision fractional digits.
+//
+// When the operands of a binary operator differ, the one with the lower rank
+// is promoted: int64 < bigint < decimal < float64. Big values are immutable;
+// every operation allocates its result.
+
+const decimalPrecision = 28
+
+// maxBigBits bounds the size of the bigints, and of the numerators and
+// denominators of the decimals, produced by ** and <<, so that a script
+// cannot exhaust the memory of the process with a single operation
+const maxBigBits = 1 << 24
+
+const numberTooLarge = "Number is too large."
+
+// powerTooLarge reports whether base**exp would have more than maxBigBits
+// bits. The estimate is exact for powers of two and low otherwise.
+func powerTooLarge(base *big.Int, exp *big.Int) bool {
+	bits := int64(base.BitLen() - 1)
+	if bits <= 0 {
+		return false // 0, 1 and -1 keep their size
+	}
+	return !exp.IsInt64() || exp.Int64() > maxBigBits/bits
+}
+
+const (
+	rankInteger = iota
+	rankBigInt
+	rankDecimal
+	rankFloat
+)
+
+func numberRank(value interface{}) int {
+	switch value.(type) {
+	case int64:
+		return rankInteger
+	case *big.Int:
+		return rankBigInt
+	case *big.Rat:
+		return rankDecimal
+	}
+	return rankFloat
+}
+
+// toBigInt converts an int64 or a bigint to a bigint
+func toBigInt(value interface{}) *big.Int {
+	if integer, ok := value.(int64); ok {
+		return big.NewInt(integer)
+	}
+	return value.(*big.Int)
+}
+
+// toDecimal converts an int64, a bigint or a decimal to a decimal
+func toDecimal(value interface{}) *big.Rat {
+	switch v := value.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(v)
+	case *big.Int:
+		return new(big.Rat).SetInt(v)
+	}
+	return value.(*big.Rat)
+}
+
+// bigIntArithmetic evaluates - + * / % and ** on bigints. '/' yields a
+// decimal so that the division stays exact.
+func bigIntArithmetic(operator token.Token, lhs *big.Int, rhs *big.Int) (interface{}, error) {
+	switch operator.Type {
+	case token.PLUS:
+		return new(big.Int).Add(lhs, rhs), nil
+	case token.MINUS:
+		return new(big.Int).Sub(lhs, rhs), nil
+	case token.STAR:
+		return new(big.Int).Mul(lhs, rhs), nil
+	case token.SLASH:
+		return decimalArithmetic(operator, new(big.Rat).SetInt(lhs), new(big.Rat).SetInt(rhs))
+	case token.PERCENT:
+		if rhs.Sign() == 0 {
+			return nil, runtimeerror.Make(operator, divisionByZero)
+		}
+		mod := new(big.Int).Rem(lhs, rhs)
+		if mod.Sign() != 0 && mod.Sign() != rhs.Sign() {
+			mod.Add(mod, rhs)
+		}
+		return mod, nil
+	}
+	if rhs.Sign() < 0 {
+		return decimalArithmetic(operator, new(big.Rat).SetInt(lhs), new(big.Rat).SetInt(rhs))
+	}
+	if powerTooLarge(lhs, rhs) {
+		return nil, runtimeerror.Make(operator, numberTooLarge)
+	}
+	return new(big.Int).Exp(lhs, rhs, nil), nil
+}
+
+// decimalArithmetic evaluates - + * / % and ** on decimals. A non-integral
+// exponent falls back to float64.
+func decimalArithmetic(operator token.Token, lhs *big.Rat, rhs *big.Rat) (interface{}, error) {
+	switch operator.Type {
+	case token.PLUS:
+		return new(big.Rat).Add(lhs, rhs), nil
+	case token.MINUS:
+		return new(big.Rat).Sub(lhs, rhs), nil
+	case token.STAR:
+		return new(big.Rat).Mul(lhs, rhs), nil
+	case token.SLASH:
+		if rhs.Sign() == 0 {
+			return nil, runtimeerror.Make(operator, divisionByZero)
+		}
+		return roundDecimal(new(big.Rat).Quo(lhs, rhs), decimalPrecision), nil
+	case token.PERCENT:
+		if rhs.Sign() == 0 {
+			return nil, runtimeerror.Make(operator, divisionByZero)
+		}
+		quotient := new(big.Rat).SetInt(floorRat(new(big.Rat).Quo(lhs, rhs)))
+		return new(big.Rat).Sub(lhs, quotient.Mul(quotient, rhs)), nil
+	}
+	if !rhs.IsInt() || !rhs.Num().IsInt64() {
+		l, _ := lhs.Float64()
+		r, _ := rhs.Float64()
+		return math.Pow(l, r), nil
+	}
+	if magnitude := new(big.Int).Abs(rhs.Num()); powerTooLarge(lhs.Num(), magnitude) || powerTooLarge(lhs.Denom(), magnitude) {
+		return nil, runtimeerror.Make(operator, numberTooLarge)
+	}
+	exp := rhs.Num().Int64()
+	if exp < 0 {
+		if lhs.Sign() == 0 {
+			return nil, runtimeerror.Make(operator, divisionByZero)
+		}
+		return roundDecimal(new(big.Rat).Inv(powRat(lhs, -exp)), decimalPrecision), nil
+	}
+	return powRat(lhs, exp), nil
+}
+
+// powRat computes base**exp for exp >= 0
+func powRat(base *big.Rat, exp int64) *big.Rat {
+	e := big.NewInt(exp)
+	num := new(big.Int).Exp(base.Num(), e, nil)
+	denom := new(big.Int).Exp(base.Denom(), e, nil)
+	return new(big.Rat).SetFrac(num, denom)
+}
+
+// floorRat returns the largest integer not greater than r
+func floorRat(r *big.Rat) *big.Int {
+	// the denominator is always positive, so Euclidean division is a floor
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+// roundDecimal rounds r half to even at the given number of fractional digits
+func roundDecimal(r *big.Rat, digits int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	scaled := new(big.Int).Mul(r.Num(), scale)
+	quotient, remainder := new(big.Int).QuoRem(scaled, r.Denom(), new(big.Int))
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	if cmp := twice.Cmp(r.Denom()); cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1) {
+		if scaled.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return new(big.Rat).SetFrac(quotient, scale)
+}
+
+// compareNumbers orders two numbers. ok is false if either is NaN.
+func compareNumbers(left interface{}, right interface{}) (cmp int, ok bool) {
+	rank := numberRank(left)
+	if r := numberRank(right); r > rank {
+		rank = r
+	}
+	switch rank {
+	case rankInteger:
+		lhs, rhs := left.(int64), right.(int64)
+		if lhs < rhs {
+			return -1, true
+		} else if lhs > rhs {
+			return 1, true
+		}
+		return 0, true
+	case rankBigInt:
+		return toBigInt(left).Cmp(toBigInt(right)), true
+	case rankDecimal:
+		return toDecimal(left).Cmp(toDecimal(right)), true
+	}
+	l, _ := toFloat(left)
+	r, _ := toFloat(right)
+	if math.IsNaN(l) || math.IsNaN(r) {
+		return 0, false
+	}
+	if exactFloat(left) && exactFloat(right) {
+		if l < r {
+			return -1, true
+		} else if l > r {
+			return 1, true
+		}
+		return 0, true
+	}
+	// a float and an integer or a decimal that float64 cannot represent are
+	// compared exactly, so that == agrees with the keys of maps
+	if li, ri := infinity(left), infinity(right); li != 0 || ri != 0 {
+		return li - ri, true
+	}
+	return toExact(left).Cmp(toExact(right)), true
+}
+
+// exactFloat reports whether toFloat converts a number without rounding
+// cheaply: a float, or an int64 of at most 53 bits
+func exactFloat(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return true
+	case int64:
+		return v >= -1<<53 && v <= 1<<53
+	}
+	return false
+}
+
+// infinity returns 1 for inf, -1 for -inf and 0 for any other number
+func infinity(value interface{}) int {
+	if f, ok := value.(float64); ok && math.IsInf(f, 0) {
+		if f > 0 {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// toExact converts a finite number to a rational without rounding
+func toExact(value interface{}) *big.Rat {
+	if f, ok := value.(float64); ok {
+		return new(big.Rat).SetFloat64(f)
+	}
+	return toDecimal(value)
+}
+
+// formatDecimal renders a decimal exactly, without trailing zeros
+func formatDecimal(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// the denominator is 2**a * 5**b and the expansion has max(a, b) digits
+	denom := new(big.Int).Set(r.Denom())
+	one, ten := big.NewInt(1), big.NewInt(10)
+	digits := 0
+	for denom.Cmp(one) != 0 {
+		divisor := new(big.Int).GCD(nil, nil, denom, ten)
+		if divisor.Cmp(one) == 0 {
+			// not a terminating decimal; Lox decimals are rounded before
+			return roundDecimal(r, decimalPrecision).FloatString(decimalPrecision)
+		}
+		denom.Quo(denom, divisor)
+		digits++
+	}
+	return r.FloatString(digits)
+}
+
+// parseBigInt parses a base 10 integer, with an optional 'n' suffix
+func parseBigInt(s string) (*big.Int, bool) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "n")
+	return new(big.Int).SetString(s, 10)
+}
+
+// parseDecimal parses a decimal number, with an optional 'd' suffix
+func parseDecimal(s string) (*big.Rat, bool) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "d")
+	if strings.ContainsAny(s, "/") {
+		return nil, false
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, false
+	}
+	return roundDecimal(r, decimalPrecision), true
+}
+
+func init() {
+	defineNative("bigint", 1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case int64:
+			return big.NewInt(v), nil
+		case *big.Int:
+			return v, nil
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("bigint: cannot convert %s.", stringify(v))
+			}
+			integer, _ := new(big.Float).SetFloat64(math.Trunc(v)).Int(nil)
+			return integer, nil
+		case *big.Rat:
+			return new(big.Int).Quo(v.Num(), v.Denom()), nil
+		case string:
+			if integer, ok := parseBigInt(v); ok {
+				return integer, nil
+			}
+			return nil, fmt.Errorf("bigint: invalid integer %q.", v)
+		}
+		return nil, fmt.Errorf("Argument 1 of 'bigint' must be a number or a string.")
+	})
+	// decimal converts floats through their printed form, so that
+	// decimal(0.1) is exactly 0.1
+	defineNative("decimal", 1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case int64, *big.Int, *big.Rat:
+			return toDecimal(v), nil
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("decimal: cannot convert %s.", stringify(v))
+			}
+			r, _ := parseDecimal(formatNumber(v))
+			return r, nil
+		case string:
+			if r, ok := parseDecimal(v); ok {
+				return r, nil
+			}
+			return nil, fmt.Errorf("decimal: invalid number %q.", v)
+		}
+		return nil, fmt.Errorf("Argument 1 of 'decimal' must be a number or a string.")
+	})
+	defineNative("float", 1, func(args []interface{}) (interface{}, error) {
+		if number, ok := toFloat(args[0]); ok {
+			return number, nil
+		}
+		return nil, fmt.Errorf("Argument 1 of 'float' must be a number.")
+	})
+}
@@ -73,13 +73,12 @@ func (c *Class) Call(arguments []interface{}) (interface{}, error) {
 	return instance, nil
 }
 
-// Arity returns the number of allowed parameters in the class constructor
-// which is always 0
-func (c *Class) Arity() int {
+// Signature returns the parameters of the class initializer, if any
+func (c *Class) Signature() Signature {
 	if initializer, prs := c.Methods["init"]; prs {
-		return initializer.Arity()
+		return initializer.Signature()
 	}
-	return 0
+	return Signature{Names: make([]string, 0)}
 }
 
 // ClassInstance is a user defined class instance
@@ -116,3 +115,70 @@ func (c *ClassInstance) Set(name token.Token, value interface{}) (interface{}, e
 	c.fields[name.Lexeme] = value
 	return nil, nil
 }
+
+// NativeClass is a builtin class whose methods are implemented in Go. Values
+// of builtin types, such as strings and lists, find their methods through it.
+type NativeClass struct {
+	Name    string
+	Methods map[string]*NativeFunction
+}
+
+// NewNativeClass creates a builtin class without methods
+func NewNativeClass(name string) *NativeClass {
+	return &NativeClass{Name: name, Methods: make(map[string]*NativeFunction)}
+}
+
+// String ...
+func (c *NativeClass) String() string {
+	return fmt.Sprintf("<native-class %s>", c.Name)
+}
+
+// Bind looks up a method and binds it to the receiver
+func (c *NativeClass) Bind(receiver interface{}, name token.Token) (interface{}, error) {
+	if m, prs := c.Methods[name.Lexeme]; prs {
+		return m.Bind(receiver), nil
+	}
+	return nil, runtimeerror.Make(name, fmt.Sprintf("Undefined property '%s'", name.Lexeme))
+}
+
+// NativeInstance is an instance of a builtin class backed by Go state, e.g.
+// a compiled regular expression. Its methods receive the instance as their
+// first argument.
+type NativeInstance struct {
+	PropertyAccessor
+	Class *NativeClass
+	State interface{}
+}
+
+// NewNativeInstance creates an instance of a builtin class
+func NewNativeInstance(class *NativeClass, state interface{}) *NativeInstance {
+	return &NativeInstance{Class: class, State: state}
+}
+
+func (n *NativeInstance) String() string {
+	return fmt.Sprintf("<native-instance %s>", n.Class.Name)
+}
+
+// Get binds a method of the builtin class
+func (n *NativeInstance) Get(name token.Token) (interface{}, error) {
+	return n.Class.Bind(n, name)
+}
+
+// Set always fails, since native instances have no fields
+func (n *NativeInstance) Set(name token.Token, value interface{}) (interface{}, error) {
+	return nil, runtimeerror.Make(name, fmt.Sprintf("Cannot set property '%s' on a %s.", name.Lexeme, n.Class.Name))
+}
+
+// nativeClassOf returns the builtin class of a value, or nil if the value
+// does not have one
+func nativeClassOf(value interface{}) *NativeClass {
+	switch value.(type) {
+	case string:
+		return stringClass
+	case *List:
+		return listClass
+	case *Map:
+		return mapClass
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// stringify is the canonical conversion of a Lox value to a string. It is
+// used by print, str() and every other place that renders values.
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatNumber(v)
+	case *big.Int:
+		return v.String()
+	case *big.Rat:
+		return formatDecimal(v)
+	case string:
+		return v
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// formatNumber renders integral floats without a fractional part or an
+// exponent (up to 1e21), and other numbers with the shortest representation
+// that reads back to the same float64. Exponents are written the way Lox
+// literals are, without a plus sign or leading zeros: 1e21, 1e-7.
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "nan"
+	case math.IsInf(n, 1):
+		return "inf"
+	case math.IsInf(n, -1):
+		return "-inf"
+	}
+	abs := math.Abs(n)
+	if abs < 1e21 && (abs >= 1e-6 || n == 0) {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	// strip the sign and leading zeros of the exponent: 1e+21 => 1e21, 1e-07 => 1e-7
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(n, 'e', -1, 64), "e")
+	sign := strings.TrimPrefix(exponent[:1], "+")
+	return mantissa + "e" + sign + strings.TrimLeft(exponent[1:], "0")
+}
+
+// parseNumber converts a string to a number, accepting the same spellings
+// stringify produces. Integers that fit in an int64 are parsed as integers.
+func parseNumber(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	if integer, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return integer, true
+	}
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); !ok || ne.Err != strconv.ErrRange {
+			return nil, false
+		}
+	}
+	return number, true
+}
+
+// truncate converts a number to an integer, rounding towards zero
+func truncate(native string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		if integer, ok := floatToInteger(math.Trunc(v)); ok {
+			return integer, nil
+		}
+		return nil, fmt.Errorf("%s: %s is out of the integer range.", native, stringify(v))
+	case *big.Int:
+		if v.IsInt64() {
+			return v.Int64(), nil
+		}
+		return nil, fmt.Errorf("%s: %s is out of the integer range.", native, stringify(v))
+	case *big.Rat:
+		return truncate(native, new(big.Int).Quo(v.Num(), v.Denom()))
+	}
+	return nil, fmt.Errorf("Argument 1 of '%s' must be a number or a string.", native)
+}
+
+// formatValues implements printf-style formatting. The supported verbs are
+// %d %x %o %b %c (integral numbers), %f %e %g (numbers), %s %v (any value)
+// and %% for a literal percent sign.
+func formatValues(format string, args []interface{}) (string, error) {
+	var sb strings.Builder
+	next := 0
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			sb.WriteByte(c)
+			continue
+		}
+		// flags, width and precision are passed through to fmt
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+# 0", format[j]) >= 0 {
+			j++
+		}
+		for j < len(format) && (format[j] >= '0' && format[j] <= '9' || format[j] == '.') {
+			j++
+		}
+		if j >= len(format) {
+			return "", fmt.Errorf("format: incomplete verb at the end of %q.", format)
+		}
+		spec, verb := format[i:j], format[j]
+		i = j
+		if verb == '%' {
+			sb.WriteByte('%')
+			continue
+		}
+		if next >= len(args) {
+			return "", fmt.Errorf("format: missing value for %%%c.", verb)
+		}
+		arg := args[next]
+		next++
+		switch verb {
+		case 'd', 'x', 'X', 'o', 'b', 'c':
+			if bigint, ok := arg.(*big.Int); ok && verb != 'c' {
+				sb.WriteString(fmt.Sprintf(spec+string(verb), bigint))
+				continue
+			}
+			integer, ok := arg.(int64)
+			if number, isFloat := arg.(float64); isFloat {
+				integer, ok = floatToInteger(number)
+			}
+			if !ok {
+				return "", fmt.Errorf("format: %%%c expects an integer, got %s.", verb, stringify(arg))
+			}
+			sb.WriteString(fmt.Sprintf(spec+string(verb), integer))
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			if decimal, ok := arg.(*big.Rat); ok && (verb == 'f' || verb == 'F') {
+				// decimals are formatted exactly instead of through a float64
+				sb.WriteString(formatFixedDecimal(spec, decimal))
+				continue
+			}
+			number, ok := toFloat(arg)
+			if !ok {
+				return "", fmt.Errorf("format: %%%c expects a number, got %s.", verb, stringify(arg))
+			}
+			sb.WriteString(fmt.Sprintf(spec+string(verb), number))
+		case 's', 'v':
+			sb.WriteString(fmt.Sprintf(spec+"s", stringify(arg)))
+		default:
+			return "", fmt.Errorf("format: unknown verb %%%c.", verb)
+		}
+	}
+	if next != len(args) {
+		return "", fmt.Errorf("format: %d values given but %d used.", len(args), next)
+	}
+	return sb.String(), nil
+}
+
+// formatFixedDecimal formats a decimal for a %f verb, rounding it half to
+// even at the requested precision (6 by default) and honouring the width
+func formatFixedDecimal(spec string, decimal *big.Rat) string {
+	flags := strings.TrimLeft(spec, "%")
+	precision := 6
+	if dot := strings.IndexByte(flags, '.'); dot >= 0 {
+		precision, _ = strconv.Atoi(flags[dot+1:])
+		flags = flags[:dot]
+	}
+	text := roundDecimal(decimal, precision).FloatString(precision)
+	return fmt.Sprintf("%"+flags+"s", text)
+}
+
+func init() {
+	defineNative("str", 1, func(args []interface{}) (interface{}, error) {
+		return stringify(args[0]), nil
+	})
+	// num returns nil when the string is not a valid number
+	defineNative("num", 1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case int64, float64, *big.Int, *big.Rat:
+			return v, nil
+		case string:
+			if number, ok := parseNumber(v); ok {
+				return number, nil
+			}
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Argument 1 of 'num' must be a number or a string.")
+	})
+	// int truncates towards zero
+	defineNative("int", 1, func(args []interface{}) (interface{}, error) {
+		if s, ok := args[0].(string); ok {
+			number, ok := parseNumber(s)
+			if !ok {
+				return nil, fmt.Errorf("int: invalid number %q.", s)
+			}
+			return truncate("int", number)
+		}
+		return truncate("int", args[0])
+	})
+	// format(pattern, values...)
+	defineNative("format", 1, func(args []interface{}) (interface{}, error) {
+		format, err := stringArgument("format", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return formatValues(format, args[1:])
+	}).variadic = true
+	defineNative("toFixed", 2, func(args []interface{}) (interface{}, error) {
+		number, err := numberArgument("toFixed", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		digits, err := integerArgument("toFixed", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		if digits < 0 || digits > 100 {
+			return nil, fmt.Errorf("toFixed: digits must be in [0, 100], got %d.", digits)
+		}
+		return strconv.FormatFloat(number, 'f', int(digits), 64), nil
+	})
+}
@@ -4,23 +4,52 @@ import (
 	"fmt"
 	"github.com/jfourkiotis/golox/ast"
 	"github.com/jfourkiotis/golox/env"
+	"github.com/jfourkiotis/golox/runtimeerror"
 	"github.com/jfourkiotis/golox/semantic"
 	"github.com/jfourkiotis/golox/token"
 )
 
 type loxCallable func([]interface{}) (interface{}, error)
 
-// Callable is the generic interface for functions/classes in Lox
+// Callable is the generic interface for functions/classes in Lox. Call
+// receives the arguments bound by bindArguments.
 type Callable interface {
-	Arity() int
+	Signature() Signature
 	Call([]interface{}) (interface{}, error)
 }
 
+// Signature describes the arguments a callable accepts
+type Signature struct {
+	Required int      // parameters without a default value
+	Optional int      // parameters with a default value
+	Variadic bool     // whether the extra arguments are collected in a list
+	Names    []string // names of the required and optional parameters; nil if they cannot be named
+}
+
+// describe renders the permitted number of arguments
+func (s Signature) describe() string {
+	switch {
+	case s.Variadic:
+		return fmt.Sprintf("at least %d", s.Required)
+	case s.Optional > 0:
+		return fmt.Sprintf("%d to %d", s.Required, s.Required+s.Optional)
+	}
+	return fmt.Sprintf("%d", s.Required)
+}
+
+type missing struct{}
+
+// missingArgument marks an optional parameter that takes its default value
+var missingArgument = &missing{}
+
 // NativeFunction is a builtin Lox function
 type NativeFunction struct {
 	Callable
 	nativeCall loxCallable
 	arity      int
+	optional   int  // trailing arguments that may be omitted
+	variadic   bool // whether any number of extra arguments is accepted
+	name       string
 }
 
 // Call is the operation that executes a builtin function
@@ -28,14 +57,28 @@ func (n *NativeFunction) Call(arguments []interface{}) (interface{}, error) {
 	return n.nativeCall(arguments)
 }
 
-// Arity returns the number of allowed parameters for the native function
-func (n *NativeFunction) Arity() int {
-	return n.arity
+// Signature returns the number of arguments of the native function
+func (n *NativeFunction) Signature() Signature {
+	return Signature{Required: n.arity, Optional: n.optional, Variadic: n.variadic}
 }
 
 // String returns the name of the native function
 func (n *NativeFunction) String() string {
-	return fmt.Sprintf("<native/%p>", n.nativeCall)
+	return fmt.Sprintf("<native/%s>", n.name)
+}
+
+// Bind creates a method of a builtin class. The receiver is passed to the
+// native function as its first argument.
+func (n *NativeFunction) Bind(receiver interface{}) *NativeFunction {
+	return &NativeFunction{
+		name:     n.name,
+		arity:    n.arity - 1,
+		optional: n.optional,
+		variadic: n.variadic,
+		nativeCall: func(args []interface{}) (interface{}, error) {
+			return n.nativeCall(append([]interface{}{receiver}, args...))
+		},
+	}
 }
 
 // UserFunction are functions defined in Lox code
@@ -46,6 +89,7 @@ type UserFunction struct {
 	Resolution    semantic.Resolution
 	IsInitializer bool
 	envSize       int
+	className     string // empty for functions that are not methods
 }
 
 // NewUserFunction creates a new UserFunction
@@ -59,10 +103,35 @@ func (u *UserFunction) Call(arguments []interface{}) (interface{}, error) {
 
 	if !u.Definition.IsProperty() {
 		for i, param := range u.Definition.Params {
-			env.Define(param.Lexeme, arguments[i], i)
+			var value interface{} = missingArgument
+			if i < len(arguments) {
+				value = arguments[i]
+			}
+			if value == missingArgument {
+				if u.Definition.Variadic && i == len(u.Definition.Params)-1 {
+					value = NewList(make([]interface{}, 0))
+				} else {
+					// defaults are evaluated on every call, after the
+					// parameters before them are bound
+					var err error
+					if value, err = Eval(u.Definition.Defaults[i], env, u.Resolution); err != nil {
+						return nil, err
+					}
+				}
+			}
+			env.Define(param.Lexeme, value, i)
 		}
 	}
 
+	if u.Definition.IsGenerator {
+		return newGenerator(u, env), nil
+	}
+	return u.run(env)
+}
+
+// run executes the body of the function in an environment holding its
+// arguments
+func (u *UserFunction) run(env *env.Environment) (interface{}, error) {
 	for _, stmt := range u.Definition.Body {
 		_, err := Eval(stmt, env, u.Resolution)
 
@@ -83,9 +152,80 @@ func (u *UserFunction) Call(arguments []interface{}) (interface{}, error) {
 	return nil, nil
 }
 
-// Arity returns the number of arguments of the user-defined function
-func (u *UserFunction) Arity() int {
-	return len(u.Definition.Params)
+// Signature describes the parameters of the user-defined function
+func (u *UserFunction) Signature() Signature {
+	sig := Signature{Names: make([]string, 0, len(u.Definition.Params))}
+	for i, param := range u.Definition.Params {
+		if u.Definition.Variadic && i == len(u.Definition.Params)-1 {
+			sig.Variadic = true
+		} else if u.Definition.Defaults[i] != nil {
+			sig.Optional++
+			sig.Names = append(sig.Names, param.Lexeme)
+		} else {
+			sig.Required++
+			sig.Names = append(sig.Names, param.Lexeme)
+		}
+	}
+	return sig
+}
+
+type namedArgument struct {
+	name  token.Token
+	value interface{}
+}
+
+// bindArguments matches the positional and named arguments of a call to the
+// signature of function. Optional parameters without an argument are bound
+// to missingArgument, and the extra arguments of a variadic function are
+// collected in a list.
+func bindArguments(function Callable, paren token.Token, positional []interface{}, named []namedArgument) ([]interface{}, error) {
+	sig := function.Signature()
+	fixed := sig.Required + sig.Optional
+	if (len(positional) > fixed && !sig.Variadic) || (len(named) == 0 && len(positional) < sig.Required) {
+		return nil, runtimeerror.Make(paren, fmt.Sprintf("Expected %s arguments but got %d.", sig.describe(), len(positional)+len(named)))
+	}
+	if len(named) > 0 && sig.Names == nil {
+		return nil, runtimeerror.Make(paren, fmt.Sprintf("'%s' does not accept named arguments.", callableName(function)))
+	}
+	if sig.Names == nil {
+		return positional, nil
+	}
+
+	args := make([]interface{}, fixed)
+	for i := range args {
+		if i < len(positional) {
+			args[i] = positional[i]
+		} else {
+			args[i] = missingArgument
+		}
+	}
+	for _, arg := range named {
+		index := -1
+		for i, name := range sig.Names {
+			if name == arg.name.Lexeme {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, runtimeerror.Make(arg.name, fmt.Sprintf("'%s' has no parameter named '%s'.", callableName(function), arg.name.Lexeme))
+		} else if args[index] != missingArgument {
+			return nil, runtimeerror.Make(arg.name, fmt.Sprintf("Got multiple values for argument '%s'.", arg.name.Lexeme))
+		}
+		args[index] = arg.value
+	}
+	for i := 0; i < sig.Required; i++ {
+		if args[i] == missingArgument {
+			return nil, runtimeerror.Make(paren, fmt.Sprintf("Missing argument '%s'.", sig.Names[i]))
+		}
+	}
+	if sig.Variadic {
+		rest := make([]interface{}, 0)
+		if len(positional) > fixed {
+			rest = append(rest, positional[fixed:]...)
+		}
+		args = append(args, NewList(rest))
+	}
+	return args, nil
 }
 
 // String returns the name of the user-function
@@ -93,9 +233,18 @@ func (u *UserFunction) String() string {
 	return u.Definition.Name.Lexeme
 }
 
+// QualifiedName returns the function name, prefixed by the class name for
+// methods (e.g. "Fib.find")
+func (u *UserFunction) QualifiedName() string {
+	if u.className != "" {
+		return u.className + "." + u.Definition.Name.Lexeme
+	}
+	return u.Definition.Name.Lexeme
+}
+
 // Bind creates a new instance method
 func (u *UserFunction) Bind(instance *ClassInstance) *UserFunction {
 	thisEnv := env.NewSized(u.Closure, 1)
 	thisEnv.Define("this", instance, 0)
-	return &UserFunction{Definition: u.Definition, Closure: thisEnv, Resolution: u.Resolution, envSize: u.envSize, IsInitializer: u.IsInitializer}
+	return &UserFunction{Definition: u.Definition, Closure: thisEnv, Resolution: u.Resolution, envSize: u.envSize, IsInitializer: u.IsInitializer, className: u.className}
 }
@@ -0,0 +1,201 @@
+package interpreter
+
+import (
+	"fmt"
+	"github.com/jfourkiotis/golox/env"
+	"runtime"
+	"sync"
+)
+
+// Calling a function that contains a yield statement returns a generator.
+// The body of the function runs on a goroutine of its own, which is started
+// by the first request for a value. The consumer and the goroutine hand
+// control to each other over channels, so only one of them runs at a time.
+//
+// A suspended goroutine is a GC root, and so is everything its environment
+// reaches. A generator is therefore stopped explicitly: by close(), when a
+// for-in loop over it exits, or by CloseGenerators at the end of a script.
+// The finalizer of the Lox-visible instance only covers generators whose
+// environment does not reach the instance itself.
+
+var generatorClass = NewNativeClass("Generator")
+
+type generatorResult struct {
+	value interface{}
+	done  bool
+	err   error
+}
+
+// closeError unwinds the body of a closed generator
+type closeError struct {
+	error
+}
+
+type generatorState struct {
+	function    *UserFunction
+	environment *env.Environment
+	resume      chan bool // false asks a suspended generator to stop
+	results     chan generatorResult
+	started     bool
+	running     bool
+	done        bool
+	peeked      bool // hasNext() fetched a value that next() has not consumed
+	peekedValue interface{}
+}
+
+// newGenerator creates a suspended generator for a call of function, whose
+// arguments are bound in environment
+func newGenerator(function *UserFunction, environment *env.Environment) *NativeInstance {
+	state := &generatorState{
+		function:    function,
+		environment: environment,
+		resume:      make(chan bool),
+		results:     make(chan generatorResult, 1), // the last result never blocks
+	}
+	environment.Define("yield", state, len(function.Definition.Params))
+	generator := NewNativeInstance(generatorClass, state)
+	runtime.SetFinalizer(generator, func(*NativeInstance) {
+		closingGenerators.Lock()
+		defer closingGenerators.Unlock()
+		state.close()
+	})
+	return generator
+}
+
+// liveGenerators are the generators whose goroutine has started and not
+// exited yet
+var liveGenerators = struct {
+	sync.Mutex
+	states map[*generatorState]bool
+}{states: make(map[*generatorState]bool)}
+
+// closingGenerators serializes closing from the finalizer goroutine
+var closingGenerators sync.Mutex
+
+func (g *generatorState) setLive(live bool) {
+	liveGenerators.Lock()
+	defer liveGenerators.Unlock()
+	if live {
+		liveGenerators.states[g] = true
+	} else {
+		delete(liveGenerators.states, g)
+	}
+}
+
+// CloseGenerators stops the goroutines of all suspended generators. It is
+// called when a script ends; the generators cannot be resumed afterwards.
+func CloseGenerators() {
+	liveGenerators.Lock()
+	states := make([]*generatorState, 0, len(liveGenerators.states))
+	for state := range liveGenerators.states {
+		states = append(states, state)
+	}
+	liveGenerators.Unlock()
+
+	closingGenerators.Lock()
+	defer closingGenerators.Unlock()
+	for _, state := range states {
+		state.close()
+	}
+}
+
+// run executes the body of the generator function on its own goroutine
+func (g *generatorState) run() {
+	_, err := g.function.run(g.environment)
+	if _, ok := err.(closeError); ok {
+		err = nil
+	}
+	g.results <- generatorResult{done: true, err: err}
+}
+
+// yield is called by the generator goroutine. It blocks until the consumer
+// asks for the next value or closes the generator.
+func (g *generatorState) yield(value interface{}) error {
+	g.results <- generatorResult{value: value}
+	if resume := <-g.resume; !resume {
+		return closeError{}
+	}
+	return nil
+}
+
+// advance resumes the generator until it yields a value or finishes
+func (g *generatorState) advance() (interface{}, bool, error) {
+	if g.done {
+		return nil, true, nil
+	} else if g.running {
+		return nil, false, fmt.Errorf("Generator is already running.")
+	}
+	g.running = true
+	if !g.started {
+		g.started = true
+		g.setLive(true)
+		go g.run()
+	} else {
+		g.resume <- true
+	}
+	result := <-g.results
+	g.running = false
+	if result.done {
+		g.done = true
+		g.setLive(false)
+	}
+	return result.value, result.done, result.err
+}
+
+// next returns the value fetched by hasNext(), or the next value
+func (g *generatorState) next() (interface{}, bool, error) {
+	if g.peeked {
+		value := g.peekedValue
+		g.peeked, g.peekedValue = false, nil
+		return value, false, nil
+	}
+	return g.advance()
+}
+
+func (g *generatorState) hasNext() (bool, error) {
+	if !g.peeked {
+		value, done, err := g.advance()
+		if err != nil || done {
+			return false, err
+		}
+		g.peeked, g.peekedValue = true, value
+	}
+	return true, nil
+}
+
+// close stops a suspended generator, waiting for its goroutine to exit
+func (g *generatorState) close() error {
+	if g.running {
+		return fmt.Errorf("Generator is already running.")
+	}
+	if g.started && !g.done {
+		g.resume <- false
+		<-g.results
+		g.setLive(false)
+	}
+	g.done = true
+	g.peeked, g.peekedValue = false, nil
+	return nil
+}
+
+func defineGeneratorMethod(name string, call func(g *generatorState) (interface{}, error)) {
+	generatorClass.Methods[name] = &NativeFunction{name: name, arity: 1, nativeCall: func(args []interface{}) (interface{}, error) {
+		return call(args[0].(*NativeInstance).State.(*generatorState))
+	}}
+}
+
+func init() {
+	defineGeneratorMethod("hasNext", func(g *generatorState) (interface{}, error) {
+		return g.hasNext()
+	})
+	defineGeneratorMethod("next", func(g *generatorState) (interface{}, error) {
+		value, done, err := g.next()
+		if err == nil && done {
+			return nil, fmt.Errorf("Generator is exhausted.")
+		}
+		return value, err
+	})
+	defineGeneratorMethod("close", func(g *generatorState) (interface{}, error) {
+		return nil, g.close()
+	})
+}
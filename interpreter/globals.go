@@ -1,24 +1,65 @@
 package interpreter
 
 import (
+	"fmt"
 	"github.com/jfourkiotis/golox/env"
-	"time"
 )
 
 // GlobalEnv is the global environment
 var GlobalEnv = env.NewGlobal()
 var globals = GlobalEnv
 
-func init() {
-	GlobalEnv.Define("clock", &NativeFunction{
-		arity: 0,
-		nativeCall: func(args []interface{}) (interface{}, error) {
-			return time.Now().Second(), nil
-		},
-	}, -1)
-}
-
 // ResetGlobalEnv resets the GlobalEnv to its original reference
 func ResetGlobalEnv() {
 	GlobalEnv = globals
 }
+
+// defineNative binds a builtin function to a name in the global environment
+func defineNative(name string, arity int, call loxCallable) *NativeFunction {
+	function := &NativeFunction{name: name, arity: arity, nativeCall: call}
+	globals.Define(name, function, -1)
+	return function
+}
+
+// numberArgument returns the i-th argument of a native function, which must
+// be a number, as a float64
+func numberArgument(name string, args []interface{}, i int) (float64, error) {
+	if number, ok := toFloat(args[i]); ok {
+		return number, nil
+	}
+	return 0, fmt.Errorf("Argument %d of '%s' must be a number.", i+1, name)
+}
+
+// integerArgument returns the i-th argument of a native function, which must
+// be an integer or an integral float
+func integerArgument(name string, args []interface{}, i int) (int64, error) {
+	if integer, ok := args[i].(int64); ok {
+		return integer, nil
+	}
+	number, err := numberArgument(name, args, i)
+	if err != nil {
+		return 0, err
+	}
+	if integer, ok := floatToInteger(number); ok {
+		return integer, nil
+	}
+	return 0, fmt.Errorf("Argument %d of '%s' must be an integer.", i+1, name)
+}
+
+// stringArgument returns the i-th argument of a native function, which must
+// be a string
+func stringArgument(name string, args []interface{}, i int) (string, error) {
+	if str, ok := args[i].(string); ok {
+		return str, nil
+	}
+	return "", fmt.Errorf("Argument %d of '%s' must be a string.", i+1, name)
+}
+
+// listArgument returns the i-th argument of a native function, which must
+// be a list
+func listArgument(name string, args []interface{}, i int) (*List, error) {
+	if list, ok := args[i].(*List); ok {
+		return list, nil
+	}
+	return nil, fmt.Errorf("Argument %d of '%s' must be a list.", i+1, name)
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/jfourkiotis/golox/semantic"
 	"github.com/jfourkiotis/golox/token"
 	"io"
-	"math"
 	"os"
 )
 
@@ -20,9 +19,44 @@ const (
 // Options contains customization points for the interpreter behavior
 type Options struct {
 	Writer io.Writer
+	// Reader is the standard input of scripts
+	Reader io.Reader
+	// Args are the script arguments returned by args()
+	Args []string
+	// ScriptName is the file name reported in stack traces
+	ScriptName string
+	// FileRoot is the directory the io natives are confined to. File access
+	// is denied when it is empty.
+	FileRoot string
+	// Clock is the time source of clock(), now() and sleep()
+	Clock Clock
 }
 
-var options = &Options{Writer: os.Stdout}
+var options = &Options{Writer: os.Stdout, Reader: os.Stdin, ScriptName: defaultScriptName, Clock: systemClock{}}
+
+const (
+	defaultScriptName = "<stdin>"
+	scriptFrameName   = "<script>"
+)
+
+// SetOptions replaces the interpreter options. Fields that are left unset
+// keep their default values.
+func SetOptions(opts Options) {
+	if opts.Writer == nil {
+		opts.Writer = os.Stdout
+	}
+	if opts.Reader == nil {
+		opts.Reader = os.Stdin
+	}
+	if opts.ScriptName == "" {
+		opts.ScriptName = defaultScriptName
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	options = &opts
+	startTime = opts.Clock.Now()
+}
 
 // return
 type returnError struct {
@@ -33,25 +67,65 @@ type returnError struct {
 // break
 type breakError struct {
 	error
+	label string // empty for the innermost loop
 }
 
 // continue
 type continueError struct {
 	error
+	label string // empty for the innermost loop
+}
+
+// breaks reports whether err breaks out of the loop with the given label
+func breaks(err error, label token.Token) bool {
+	b, ok := err.(breakError)
+	return ok && (b.label == "" || b.label == label.Lexeme)
+}
+
+// continues reports whether err continues the loop with the given label
+func continues(err error, label token.Token) bool {
+	c, ok := err.(continueError)
+	return ok && (c.label == "" || c.label == label.Lexeme)
+}
+
+// nil found by '?.', short-circuits the rest of an optional chain
+type optionalChainError struct {
+	error
+}
+
+// ExitError is returned by Interpret when a script calls exit(code)
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
 
 // Interpret tries to calculate the result of an expression, or print a message
-// if an error occurs
-func Interpret(statements []ast.Stmt, env *env.Environment, res semantic.Resolution) {
+// if an error occurs. The first runtime error is returned, so that embedders
+// can inspect its *runtimeerror.Error stack trace. If the script calls
+// exit(code), execution stops and an ExitError is returned.
+func Interpret(statements []ast.Stmt, env *env.Environment, res semantic.Resolution) error {
 	OldGlobalEnv := GlobalEnv
 	GlobalEnv = env
+	defer func() { GlobalEnv = OldGlobalEnv }()
+	var first error
 	for _, stmt := range statements {
 		_, err := Eval(stmt, env, res)
-		if err != nil {
+		if exit, ok := err.(ExitError); ok {
+			return exit
+		} else if err != nil {
+			if rerr, ok := err.(*runtimeerror.Error); ok {
+				rerr.AddFrame(scriptFrameName, options.ScriptName)
+			}
 			runtimeerror.Print(err.Error())
+			if first == nil {
+				first = err
+			}
 		}
 	}
-	GlobalEnv = OldGlobalEnv
+	return first
 }
 
 // Eval evaluates the given AST
@@ -66,13 +140,11 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 		if err != nil {
 			return right, err
 		} else if n.Operator.Type == token.MINUS {
-			err := checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return -right.(float64), nil
+			return negate(n.Operator, right)
 		} else if n.Operator.Type == token.BANG {
 			return !isTruthy(right), nil
+		} else if n.Operator.Type == token.TILDE {
+			return complement(n.Operator, right)
 		}
 	case *ast.Binary:
 		left, err := Eval(n.Left, environment, res)
@@ -83,112 +155,10 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 		if err != nil {
 			return right, err
 		}
-		switch n.Operator.Type {
-		case token.MINUS:
-			err := checkNumberOperand(n.Operator, left, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			err = checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return left.(float64) - right.(float64), nil
-		case token.PLUS:
-			switch lhs := left.(type) {
-			case float64:
-				switch rhs := right.(type) {
-				case float64:
-					return lhs + rhs, nil
-				}
-			case string:
-				switch rhs := right.(type) {
-				case string:
-					return lhs + rhs, nil
-				}
-			}
-			return nil, fmt.Errorf("%s", operandsMustBeTwoNumbersOrTwoStrings)
-		case token.SLASH:
-			err := checkNumberOperand(n.Operator, left, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			err = checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return left.(float64) / right.(float64), nil
-		case token.STAR:
-			err := checkNumberOperand(n.Operator, left, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			err = checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return left.(float64) * right.(float64), nil
-		case token.POWER:
-			err := checkNumberOperand(n.Operator, left, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			err = checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return math.Pow(left.(float64), right.(float64)), nil
-		case token.GREATER:
-			err := checkNumberOperand(n.Operator, left, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			err = checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return left.(float64) > right.(float64), nil
-		case token.GREATEREQUAL:
-			err := checkNumberOperand(n.Operator, left, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			err = checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return left.(float64) >= right.(float64), nil
-		case token.LESS:
-			err := checkNumberOperand(n.Operator, left, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			err = checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return left.(float64) < right.(float64), nil
-		case token.LESSEQUAL:
-			err := checkNumberOperand(n.Operator, left, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			err = checkNumberOperand(n.Operator, right, operandMustBeANumber)
-			if err != nil {
-				return nil, err
-			}
-			return left.(float64) <= right.(float64), nil
-		case token.BANGEQUAL:
-			return !isEqual(left, right), nil
-		case token.EQUALEQUAL:
-			return isEqual(left, right), nil
-		case token.COMMA:
-			_, err := Eval(n.Left, environment, res)
-			if err != nil {
-				return nil, err
-			}
-			return Eval(n.Right, environment, res)
+		if n.Operator.Type == token.COMMA {
+			return right, nil
 		}
+		return binary(n.Operator, left, right)
 	case *ast.Ternary:
 		cond, err := Eval(n.Condition, environment, res)
 		if err != nil {
@@ -203,7 +173,7 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 		if err != nil {
 			return value, err
 		}
-		fmt.Fprintln(options.Writer, value)
+		fmt.Fprintln(options.Writer, stringify(value))
 		return nil, nil
 	case *ast.Expression:
 		r, err := Eval(n.Expression, environment, res)
@@ -212,12 +182,19 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 		}
 		return nil, nil
 	case *ast.Var:
+		if err := environment.CheckRedeclaration(n.Name, n.EnvIndex); err != nil {
+			return nil, err
+		}
 		if n.Initializer != nil {
 			value, err := Eval(n.Initializer, environment, res)
 			if err != nil {
 				return nil, err
 			}
-			environment.Define(n.Name.Lexeme, value, n.EnvIndex)
+			if n.Const {
+				environment.DefineConstant(n.Name.Lexeme, value, n.EnvIndex)
+			} else {
+				environment.Define(n.Name.Lexeme, value, n.EnvIndex)
+			}
 		} else {
 			environment.DefineUnitialized(n.Name.Lexeme, n.EnvIndex)
 		}
@@ -245,6 +222,51 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			return nil, err
 		}
 		return value, nil
+	case *ast.CompoundAssign:
+		return compoundAssign(n, environment, res)
+	case *ast.ListLiteral:
+		elements := make([]interface{}, len(n.Elements))
+		for i, element := range n.Elements {
+			value, err := Eval(element, environment, res)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return NewList(elements), nil
+	case *ast.Destructure:
+		value, err := Eval(n.Initializer, environment, res)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]token.Token, len(n.Bindings))
+		for i, binding := range n.Bindings {
+			names[i] = binding.Name
+		}
+		var values []interface{}
+		if n.Pattern.Type == token.LEFTBRACKET {
+			values, err = unpackList(n.Pattern, value, len(names))
+		} else {
+			values, err = unpackProperties(n.Pattern, value, names)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, binding := range n.Bindings {
+			if err := environment.CheckRedeclaration(binding.Name, binding.EnvIndex); err != nil {
+				return nil, err
+			}
+		}
+		for i, binding := range n.Bindings {
+			if n.Const {
+				environment.DefineConstant(binding.Name.Lexeme, values[i], binding.EnvIndex)
+			} else {
+				environment.Define(binding.Name.Lexeme, values[i], binding.EnvIndex)
+			}
+		}
+		return nil, nil
+	case *ast.DestructureAssign:
+		return destructureAssign(n, environment, res)
 	case *ast.Block:
 		newEnvironment := env.NewSized(environment, n.EnvSize)
 		for _, stmt := range n.Statements {
@@ -290,9 +312,9 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			_, err := Eval(n.Statement, environment, res)
 
 			if err != nil {
-				if _, ok := err.(breakError); ok {
+				if breaks(err, n.Label) {
 					break
-				} else if _, ok := err.(continueError); ok {
+				} else if continues(err, n.Label) {
 					if n.Increment != nil {
 						_, err2 := Eval(n.Increment, environment, res)
 						if err2 != nil {
@@ -312,6 +334,44 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			}
 		}
 		return nil, nil
+	case *ast.ForIn:
+		iterable, err := Eval(n.Iterable, environment, res)
+		if err != nil {
+			return nil, err
+		}
+		next, err := iterate(iterable, n.Keyword)
+		if err != nil {
+			return nil, err
+		}
+		if generator, ok := iterable.(*NativeInstance); ok {
+			if state, ok := generator.State.(*generatorState); ok {
+				// a loop that exits early stops the generator, so that its
+				// goroutine does not outlive the loop
+				defer state.close()
+			}
+		}
+		for {
+			value, done, err := next()
+			if err != nil {
+				return nil, err
+			} else if done {
+				break
+			}
+
+			loopEnvironment := env.NewSized(environment, n.EnvSize)
+			loopEnvironment.Define(n.Name.Lexeme, value, n.EnvIndex)
+			_, err = Eval(n.Statement, loopEnvironment, res)
+
+			if err != nil {
+				if breaks(err, n.Label) {
+					break
+				} else if continues(err, n.Label) {
+					continue
+				}
+				return nil, err
+			}
+		}
+		return nil, nil
 	case *ast.While:
 		for {
 			condition, err := Eval(n.Condition, environment, res)
@@ -325,15 +385,36 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			_, err = Eval(n.Statement, environment, res)
 
 			if err != nil {
-				if _, ok := err.(breakError); ok {
+				if breaks(err, n.Label) {
 					break
-				} else if _, ok := err.(continueError); ok {
+				} else if continues(err, n.Label) {
 					continue
 				}
 				return nil, err
 			}
 		}
 		return nil, nil
+	case *ast.DoWhile:
+		for {
+			_, err := Eval(n.Statement, environment, res)
+
+			if err != nil {
+				if breaks(err, n.Label) {
+					break
+				} else if !continues(err, n.Label) {
+					return nil, err
+				}
+			}
+
+			condition, err := Eval(n.Condition, environment, res)
+			if err != nil {
+				return nil, err
+			}
+			if !isTruthy(condition) {
+				break
+			}
+		}
+		return nil, nil
 	case *ast.Logical:
 		left, err := Eval(n.Left, environment, res)
 		if err != nil {
@@ -347,6 +428,10 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			if !isTruthy(left) {
 				return left, nil
 			}
+		} else if n.Operator.Type == token.QMARKQMARK {
+			if left != nil {
+				return left, nil
+			}
 		}
 		return Eval(n.Right, environment, res)
 	case *ast.Call:
@@ -355,13 +440,32 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			return nil, err
 		}
 
-		args := make([]interface{}, 0)
+		positional := make([]interface{}, 0, len(n.Arguments))
+		var named []namedArgument
 		for _, arg := range n.Arguments {
-			a, err := Eval(arg, environment, res)
-			if err == nil {
-				args = append(args, a)
-			} else {
-				return nil, err
+			switch a := arg.(type) {
+			case *ast.Spread:
+				value, err := Eval(a.Expression, environment, res)
+				if err != nil {
+					return nil, err
+				}
+				list, ok := value.(*List)
+				if !ok {
+					return nil, runtimeerror.Make(a.Ellipsis, fmt.Sprintf("Only lists can be spread, got %s.", stringify(value)))
+				}
+				positional = append(positional, list.Elements...)
+			case *ast.NamedArgument:
+				value, err := Eval(a.Value, environment, res)
+				if err != nil {
+					return nil, err
+				}
+				named = append(named, namedArgument{name: a.Name, value: value})
+			default:
+				value, err := Eval(arg, environment, res)
+				if err != nil {
+					return nil, err
+				}
+				positional = append(positional, value)
 			}
 		}
 
@@ -371,12 +475,20 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			return nil, runtimeerror.Make(n.Paren, "Can only call functions and classes.")
 		}
 
-		if function.Arity() != len(args) {
-			return nil, runtimeerror.Make(n.Paren, fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(args)))
+		args, err := bindArguments(function, n.Paren, positional, named)
+		if err != nil {
+			return nil, err
 		}
 
-		return function.Call(args)
+		result, err := function.Call(args)
+		if err != nil {
+			return nil, unwind(err, function, n.Paren)
+		}
+		return result, nil
 	case *ast.Function:
+		if err := environment.CheckRedeclaration(n.Name, n.EnvIndex); err != nil {
+			return nil, err
+		}
 		function := NewUserFunction(n, environment, res, n.EnvSize)
 		environment.Define(n.Name.Lexeme, function, n.EnvIndex)
 		return nil, nil
@@ -390,10 +502,25 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			}
 		}
 		return nil, returnError{value: value}
+	case *ast.Match:
+		return match(n, environment, res)
+	case *ast.Yield:
+		var value interface{}
+		if n.Value != nil {
+			var err error
+			if value, err = Eval(n.Value, environment, res); err != nil {
+				return nil, err
+			}
+		}
+		generator, err := environment.GetAt(n.EnvDepth, n.Keyword, n.EnvIndex)
+		if err != nil {
+			return nil, err
+		}
+		return nil, generator.(*generatorState).yield(value)
 	case *ast.Break:
-		return nil, breakError{}
+		return nil, breakError{label: n.Label.Lexeme}
 	case *ast.Continue:
-		return nil, continueError{}
+		return nil, continueError{label: n.Label.Lexeme}
 	case *ast.Class:
 
 		var superclass *Class
@@ -408,6 +535,9 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 			}
 		}
 
+		if err := environment.CheckRedeclaration(n.Name, n.EnvIndex); err != nil {
+			return nil, err
+		}
 		environment.Define(n.Name.Lexeme, nil, n.EnvIndex)
 
 		if superclass != nil {
@@ -418,6 +548,7 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 		methods := make(map[string]*UserFunction)
 		for _, method := range n.Methods {
 			function := NewUserFunction(method, environment, res, method.EnvSize)
+			function.className = n.Name.Lexeme
 			methods[method.Name.Lexeme] = function
 			if method.Name.Lexeme == "init" {
 				function.IsInitializer = true
@@ -427,6 +558,7 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 		classmethods := make(map[string]*UserFunction)
 		for _, classmethod := range n.ClassMethods {
 			function := NewUserFunction(classmethod, environment, res, classmethod.EnvSize)
+			function.className = n.Name.Lexeme
 			classmethods[classmethod.Name.Lexeme] = function
 		}
 
@@ -444,10 +576,20 @@ func Eval(node ast.Node, environment *env.Environment, res semantic.Resolution)
 		if err != nil {
 			return nil, err
 		}
-		if accessor, ok := value.(PropertyAccessor); ok {
+		if value == nil && n.Optional {
+			return nil, optionalChainError{}
+		} else if accessor, ok := value.(PropertyAccessor); ok {
 			return accessor.Get(n.Name)
+		} else if class := nativeClassOf(value); class != nil {
+			return class.Bind(value, n.Name)
 		}
 		return nil, runtimeerror.Make(n.Name, "Only instances have properties.")
+	case *ast.OptionalChain:
+		value, err := Eval(n.Expression, environment, res)
+		if _, ok := err.(optionalChainError); ok {
+			return nil, nil
+		}
+		return value, err
 	case *ast.Set:
 		obj, err := Eval(n.Object, environment, res)
 		if err != nil {
@@ -510,13 +652,224 @@ func isEqual(left interface{}, right interface{}) bool {
 	if left == nil {
 		return false
 	}
+	if isNumber(left) && isNumber(right) {
+		return numbersEqual(left, right)
+	}
 	return left == right
 }
 
+// binary applies a binary operator to its evaluated operands
+func binary(operator token.Token, left interface{}, right interface{}) (interface{}, error) {
+	switch operator.Type {
+	case token.MINUS, token.SLASH, token.STAR, token.PERCENT, token.POWER:
+		return arithmetic(operator, left, right)
+	case token.PLUS:
+		if lhs, ok := left.(string); ok {
+			if rhs, ok := right.(string); ok {
+				return lhs + rhs, nil
+			}
+		} else if isNumber(left) && isNumber(right) {
+			return arithmetic(operator, left, right)
+		}
+		return nil, runtimeerror.Make(operator, operandsMustBeTwoNumbersOrTwoStrings)
+	case token.GREATER, token.GREATEREQUAL, token.LESS, token.LESSEQUAL:
+		return compare(operator, left, right)
+	case token.AMPERSAND, token.PIPE, token.CARET, token.LESSLESS, token.GREATERGREATER:
+		return bitwise(operator, left, right)
+	case token.BANGEQUAL:
+		return !isEqual(left, right), nil
+	case token.EQUALEQUAL:
+		return isEqual(left, right), nil
+	}
+	return nil, nil
+}
+
+// compoundOperators maps compound assignment and increment operators to the
+// binary operator they apply
+var compoundOperators = map[token.Type]token.Type{
+	token.PLUSEQUAL:    token.PLUS,
+	token.MINUSEQUAL:   token.MINUS,
+	token.STAREQUAL:    token.STAR,
+	token.SLASHEQUAL:   token.SLASH,
+	token.PERCENTEQUAL: token.PERCENT,
+	token.POWEREQUAL:   token.POWER,
+	token.PLUSPLUS:     token.PLUS,
+	token.MINUSMINUS:   token.MINUS,
+}
+
+// compoundAssign evaluates x op= value, x++ and x--. The object of a
+// property target is evaluated only once.
+func compoundAssign(n *ast.CompoundAssign, environment *env.Environment, res semantic.Resolution) (interface{}, error) {
+	operator := n.Operator
+	operator.Type = compoundOperators[n.Operator.Type]
+
+	switch target := n.Target.(type) {
+	case *ast.Variable:
+		old, err := Eval(target, environment, res)
+		if err != nil {
+			return nil, err
+		}
+		value, err := Eval(n.Value, environment, res)
+		if err != nil {
+			return nil, err
+		}
+		result, err := binary(operator, old, value)
+		if err != nil {
+			return nil, err
+		}
+		if err := assignVariable(target, result, environment); err != nil {
+			return nil, err
+		}
+		if n.Postfix {
+			return old, nil
+		}
+		return result, nil
+	case *ast.Get:
+		obj, err := Eval(target.Expression, environment, res)
+		if err != nil {
+			return nil, err
+		}
+		accessor, ok := obj.(PropertyAccessor)
+		if !ok {
+			return nil, runtimeerror.Make(target.Name, "Only instances have properties.")
+		}
+		old, err := accessor.Get(target.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := Eval(n.Value, environment, res)
+		if err != nil {
+			return nil, err
+		}
+		result, err := binary(operator, old, value)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := accessor.Set(target.Name, result); err != nil {
+			return nil, err
+		}
+		if n.Postfix {
+			return old, nil
+		}
+		return result, nil
+	}
+	return nil, nil
+}
+
+// assignVariable assigns a value to a resolved variable
+func assignVariable(variable *ast.Variable, value interface{}, environment *env.Environment) error {
+	if variable.EnvDepth >= 0 {
+		return environment.AssignAt(variable.EnvDepth, variable.EnvIndex, variable.Name, value)
+	}
+	return GlobalEnv.Assign(variable.Name, variable.EnvIndex, value)
+}
+
+// unpackList returns the elements of a list that must have count elements
+func unpackList(bracket token.Token, value interface{}, count int) ([]interface{}, error) {
+	list, ok := value.(*List)
+	if !ok {
+		return nil, runtimeerror.Make(bracket, fmt.Sprintf("Only lists can be destructured with '[', got %s.", stringify(value)))
+	}
+	if len(list.Elements) != count {
+		return nil, runtimeerror.Make(bracket, fmt.Sprintf("Expected %d values to unpack but got %d.", count, len(list.Elements)))
+	}
+	return list.Elements, nil
+}
+
+// unpackProperties returns the named properties of an instance or the
+// values of the named keys of a map
+func unpackProperties(brace token.Token, value interface{}, names []token.Token) ([]interface{}, error) {
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		switch v := value.(type) {
+		case PropertyAccessor:
+			property, err := v.Get(name)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = property
+		case *Map:
+			element, ok := v.Get(name.Lexeme)
+			if !ok {
+				return nil, runtimeerror.Make(name, fmt.Sprintf("Undefined key '%s'.", name.Lexeme))
+			}
+			values[i] = element
+		default:
+			return nil, runtimeerror.Make(brace, fmt.Sprintf("Only instances and maps can be destructured with '{', got %s.", stringify(value)))
+		}
+	}
+	return values, nil
+}
+
+// destructureAssign evaluates [a, b] = value. The value is evaluated before
+// any target is assigned, so [a, b] = [b, a] swaps a and b.
+func destructureAssign(n *ast.DestructureAssign, environment *env.Environment, res semantic.Resolution) (interface{}, error) {
+	value, err := Eval(n.Value, environment, res)
+	if err != nil {
+		return nil, err
+	}
+	values, err := unpackList(n.Bracket, value, len(n.Targets))
+	if err != nil {
+		return nil, err
+	}
+	for i, target := range n.Targets {
+		switch t := target.(type) {
+		case *ast.Variable:
+			if err := assignVariable(t, values[i], environment); err != nil {
+				return nil, err
+			}
+		case *ast.Get:
+			obj, err := Eval(t.Expression, environment, res)
+			if err != nil {
+				return nil, err
+			}
+			accessor, ok := obj.(PropertyAccessor)
+			if !ok {
+				return nil, runtimeerror.Make(t.Name, "Only instances have properties.")
+			}
+			if _, err := accessor.Set(t.Name, values[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
 func checkNumberOperand(operator token.Token, value interface{}, msg string) error {
-	switch value.(type) {
-	case int, float64:
+	if isNumber(value) {
 		return nil
 	}
-	return fmt.Errorf("%v\n[line %v]", msg, operator.Line)
+	return runtimeerror.Make(operator, msg)
+}
+
+// unwind records the frame of the function a runtime error propagated out of.
+// Plain errors returned by native functions become runtime errors at the
+// line of the call.
+func unwind(err error, function Callable, paren token.Token) error {
+	if _, ok := function.(*NativeFunction); ok {
+		switch err.(type) {
+		case *runtimeerror.Error, ExitError:
+		default:
+			err = runtimeerror.Make(paren, err.Error())
+		}
+	}
+	if rerr, ok := err.(*runtimeerror.Error); ok {
+		rerr.Unwind(callableName(function), options.ScriptName, paren.Line)
+	}
+	return err
+}
+
+func callableName(function Callable) string {
+	switch f := function.(type) {
+	case *UserFunction:
+		return f.QualifiedName()
+	case *Class:
+		if _, prs := f.Methods["init"]; prs {
+			return f.Name + ".init"
+		}
+		return f.Name
+	case *NativeFunction:
+		return f.name
+	}
+	return fmt.Sprintf("%v", function)
 }
@@ -4,12 +4,17 @@ import (
 	"github.com/jfourkiotis/golox/ast"
 	"github.com/jfourkiotis/golox/env"
 	"github.com/jfourkiotis/golox/parser"
+	"github.com/jfourkiotis/golox/runtimeerror"
 	"github.com/jfourkiotis/golox/scanner"
 	"github.com/jfourkiotis/golox/semantic"
 	"github.com/jfourkiotis/golox/token"
 	"math"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func testExpectStatementsLen(statements []ast.Stmt, length int, t *testing.T) {
@@ -40,11 +45,11 @@ func TestEvalLiteral(t *testing.T) {
 		literal  string
 		expected interface{}
 	}{
-		{"5;", 5.0},
+		{"5;", int64(5)},
 		{"false;", false},
 		{"true;", true},
 		{"\"hello\";", "hello"},
-		{"(5);", 5.0},
+		{"(5);", int64(5)},
 		{"(false);", false},
 		{"(true);", true},
 		{"(\"hello\");", "hello"},
@@ -60,7 +65,7 @@ func TestEvalUnary(t *testing.T) {
 		literal  string
 		expected interface{}
 	}{
-		{"-5;", -5.0},
+		{"-5;", int64(-5)},
 		{"!false;", true},
 		{"true;", true},
 		{"false;", false},
@@ -80,11 +85,11 @@ func TestEvalBinary(t *testing.T) {
 		literal  string
 		expected interface{}
 	}{
-		{"1 + 2;", 3.0},
-		{"1 - 2;", -1.0},
+		{"1 + 2;", int64(3)},
+		{"1 - 2;", int64(-1)},
 		{"1 / 2;", 0.5},
-		{"1 * 2;", 2.0},
-		{"2 ** 2;", 4.0},
+		{"1 * 2;", int64(2)},
+		{"2 ** 2;", int64(4)},
 		{"\"hello \" + \"world\";", "hello world"},
 		{"1 > 2;", false},
 		{"1 >= 2;", false},
@@ -110,11 +115,11 @@ func TestEvalBinaryPrecedence(t *testing.T) {
 		literal  string
 		expected interface{}
 	}{
-		{"1 - 2 - 3;", -4.0},
-		{"1 + 2 * 3;", 7.0},
-		{"2 ** 3 ** 2;", 512.0},
+		{"1 - 2 - 3;", int64(-4)},
+		{"1 + 2 * 3;", int64(7)},
+		{"2 ** 3 ** 2;", int64(512)},
 		{"-2 ** 3 ** -2;", -math.Pow(2.0, math.Pow(3.0, -2.0))},
-		{"--2;", 2.0},
+		{"- -2;", int64(2)},
 	}
 
 	for _, test := range tests {
@@ -127,8 +132,8 @@ func TestEvalTernary(t *testing.T) {
 		literal  string
 		expected interface{}
 	}{
-		{"1 ? 2 : 3;", 2.0},
-		{"nil ? 2 : 3;", 3.0},
+		{"1 ? 2 : 3;", int64(2)},
+		{"nil ? 2 : 3;", int64(3)},
 	}
 
 	for _, test := range tests {
@@ -138,6 +143,8 @@ func TestEvalTernary(t *testing.T) {
 
 func testLiteralEquality(result interface{}, expected interface{}, t *testing.T) {
 	switch r := result.(type) {
+	case int64:
+		testIntegerEquality(r, expected, t)
 	case float64:
 		testNumberEquality(r, expected, t)
 	case bool:
@@ -160,6 +167,17 @@ func testNumberEquality(lhs float64, expected interface{}, t *testing.T) {
 	}
 }
 
+func testIntegerEquality(lhs int64, expected interface{}, t *testing.T) {
+	rhs, ok := expected.(int64)
+	if !ok {
+		t.Fatalf("Expected integer. Got=%T", expected)
+	}
+
+	if rhs != lhs {
+		t.Errorf("Integers are not equal. Expected %v. Got %v", rhs, lhs)
+	}
+}
+
 func testBoolEquality(lhs bool, expected interface{}, t *testing.T) {
 	switch rhs := expected.(type) {
 	case bool:
@@ -199,18 +217,18 @@ func TestEnvironment(t *testing.T) {
 
 	if a, err := env.Get(token.Token{Lexeme: "a"}, -1); err != nil {
 		t.Fatalf("Expected variable 'a' in env")
-	} else if a.(float64) != 5.0 {
-		t.Errorf("Expected a = 5. Got %v", a.(float64))
+	} else if a.(int64) != 5 {
+		t.Errorf("Expected a = 5. Got %v", a.(int64))
 	}
 	if b, err := env.Get(token.Token{Lexeme: "b"}, -1); err != nil {
 		t.Fatalf("Expected variable 'b' in env")
-	} else if b.(float64) != 10.0 {
-		t.Errorf("Expected b = 10. Got %v", b.(float64))
+	} else if b.(int64) != 10 {
+		t.Errorf("Expected b = 10. Got %v", b.(int64))
 	}
 	if c, err := env.Get(token.Token{Lexeme: "c"}, -1); err != nil {
 		t.Fatalf("Expected variable 'c' in env")
-	} else if c.(float64) != 50.0 {
-		t.Errorf("Expected c = 50. Got %v", c.(float64))
+	} else if c.(int64) != 50 {
+		t.Errorf("Expected c = 50. Got %v", c.(int64))
 	}
 }
 
@@ -234,18 +252,18 @@ func TestEvalAssignment(t *testing.T) {
 
 	if a, err := env.Get(token.Token{Lexeme: "a"}, -1); err != nil {
 		t.Fatalf("Expected variable 'a' in env")
-	} else if a.(float64) != 2000.0 {
-		t.Errorf("Expected a = 2000. Got %v", a.(float64))
+	} else if a.(int64) != 2000 {
+		t.Errorf("Expected a = 2000. Got %v", a.(int64))
 	}
 	if b, err := env.Get(token.Token{Lexeme: "b"}, -1); err != nil {
 		t.Fatalf("Expected variable 'b' in env")
-	} else if b.(float64) != 200.0 {
-		t.Errorf("Expected b = 200. Got %v", b.(float64))
+	} else if b.(int64) != 200 {
+		t.Errorf("Expected b = 200. Got %v", b.(int64))
 	}
 	if c, err := env.Get(token.Token{Lexeme: "c"}, -1); err != nil {
 		t.Fatalf("Expected variable 'c' in env")
-	} else if c.(float64) != 20.0 {
-		t.Errorf("Expected c = 20. Got %v", c.(float64))
+	} else if c.(int64) != 20 {
+		t.Errorf("Expected c = 20. Got %v", c.(int64))
 	}
 }
 
@@ -257,7 +275,7 @@ func testInterpreterOutput(input string, expected string, t *testing.T) {
 
 	out := &strings.Builder{}
 	options.Writer = out
-	env := env.NewGlobal()
+	env := env.New(globals)
 
 	GlobalEnv = env
 	defer ResetGlobalEnv()
@@ -372,7 +390,7 @@ func TestEvalReturn(t *testing.T) {
 		
 			print fib(33);
 		}
-		`, "3.524578e+06"},
+		`, "3524578"},
 	}
 
 	for _, test := range tests {
@@ -396,8 +414,8 @@ func TestEvalGlobals(t *testing.T) {
 		e, _ := statements[0].(*ast.Expression)
 		v, _ := Eval(e.Expression, GlobalEnv, semantic.NewResolution())
 
-		if v.(int) < 0 || v.(int) > 59 {
-			t.Errorf("Expected a number in [0, 59]")
+		if v.(float64) < 0 {
+			t.Errorf("Expected a non-negative number of seconds")
 		}
 
 	}
@@ -655,3 +673,1130 @@ func TestSuperClassMethod(t *testing.T) {
 	`
 	testInterpreterOutput(input, "Fry until golden brown.", t)
 }
+
+func testInterpreterError(input string, t *testing.T) *runtimeerror.Error {
+	scanner := scanner.New(input)
+	tokens := scanner.ScanTokens()
+	parser := parser.New(tokens)
+	statements := parser.Parse()
+
+	options.Writer = &strings.Builder{}
+	env := env.New(globals)
+
+	GlobalEnv = env
+	defer ResetGlobalEnv()
+	resolution, _ := semantic.Resolve(statements)
+
+	err := Interpret(statements, GlobalEnv, resolution)
+	if err == nil {
+		t.Fatalf("Expected a runtime error")
+	}
+	rerr, ok := err.(*runtimeerror.Error)
+	if !ok {
+		t.Fatalf("Expected *runtimeerror.Error. Got=%T", err)
+	}
+	return rerr
+}
+
+func TestRuntimeErrorStackTrace(t *testing.T) {
+	input := `
+	class Fib {
+		find(n) {
+			return n + nil;
+		}
+	}
+	fun run() {
+		return Fib().find(3);
+	}
+	run();
+	`
+	err := testInterpreterError(input, t)
+
+	expected := []string{
+		"at Fib.find (<stdin>:4)",
+		"at run (<stdin>:8)",
+		"at <script> (<stdin>:10)",
+	}
+	if len(err.Trace) != len(expected) {
+		t.Fatalf("Expected %d frames. Got=%d", len(expected), len(err.Trace))
+	}
+	for i, frame := range err.Trace {
+		if frame.String() != expected[i] {
+			t.Errorf("Expected frame %q. Got %q", expected[i], frame.String())
+		}
+	}
+	if err.Line != 4 {
+		t.Errorf("Expected error at line 4. Got %d", err.Line)
+	}
+}
+
+func TestMathLibrary(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{"print sqrt(16);", "4"},
+		{"print abs(-2.5);", "2.5"},
+		{"print floor(2.7) + ceil(2.2);", "5"},
+		{"print round(2.5) + trunc(-2.7);", "1"},
+		{"print min(3, 4) + max(3, 4);", "7"},
+		{"print sin(0) + cos(0) + tan(0);", "1"},
+		{"print atan2(0, 1);", "0"},
+		{"print log(e) + exp(0);", "2"},
+		{"print pi > 3.14 and pi < 3.15;", "true"},
+		{"print inf > 1000000;", "true"},
+		{"print isNaN(nan) and !isNaN(1);", "true"},
+		{"var r = random(); print r >= 0 and r < 1;", "true"},
+		{"seed(7); var a = random(); seed(7); print a == random();", "true"},
+		{"var i = randomInt(2, 3); print i == 2 or i == 3;", "true"},
+		{"print randomInt(5, 5);", "5"},
+		{"var i = randomInt(-9e18, 9e18); print i >= -9e18 and i <= 9e18;", "true"},
+		{"var i = randomInt(-9223372036854775807 - 1, 9223372036854775807); print i == int(i);", "true"},
+		{"var i = randomInt(9223372036854775806, 9223372036854775807); print i >= 9223372036854775806;", "true"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestMathLibraryErrors(t *testing.T) {
+	err := testInterpreterError("\n\nsqrt(\"four\");", t)
+	if err.Message != "Argument 1 of 'sqrt' must be a number." {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+	if err.Line != 3 {
+		t.Errorf("Expected error at line 3. Got %d", err.Line)
+	}
+	err = testInterpreterError("randomInt(1.5, 2);", t)
+	if err.Message != "Argument 1 of 'randomInt' must be an integer." {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+}
+
+func TestStringLibrary(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print len("héllo");`, "5"},
+		{`print substr("hello", 1, 3);`, "el"},
+		{`print indexOf("héllo", "l") + indexOf("a", "b");`, "1"},
+		{`print split("a,b,c", ",");`, "[a, b, c]"},
+		{`print join(split("a,b,c", ","), "-");`, "a-b-c"},
+		{`print upper("abc") + lower("DEF");`, "ABCdef"},
+		{`print trim("  x  ");`, "x"},
+		{`print replace("a-b-c", "-", "+");`, "a+b+c"},
+		{`print startsWith("golox", "go") and endsWith("golox", "lox");`, "true"},
+		{`print repeat("ab", 3);`, "ababab"},
+		{`print charAt("héllo", 1);`, "é"},
+		{`print ord("A");`, "65"},
+		{`print chr(66);`, "B"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+
+	errors := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`repeat("ab", -1);`, "repeat: negative count -1."},
+		{`repeat("ab", 4000000000000000000);`, "repeat: result would exceed 1073741824 bytes."},
+	}
+	for _, test := range errors {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestStringMethods(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print "abc".upper();`, "ABC"},
+		{`var s = " a b "; print s.trim().len();`, "3"},
+		{`print "a,b".split(",").join(";");`, "a;b"},
+		{`var upper = "x".upper; print upper();`, "X"},
+		{`var xs = List(); xs.push(1); xs.push(2); xs.set(0, 3); print xs.pop() + xs.get(0) + xs.len();`, "6"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+
+	err := testInterpreterError(`"abc".nope();`, t)
+	if err.Message != "Undefined property 'nope'" {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+}
+
+func TestNumberPrinting(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{"print 3;", "3"},
+		{"print -2.5;", "-2.5"},
+		{"print 0.1 + 0.2;", "0.30000000000000004"},
+		{"print 1000000 * 1000000 * 1000000;", "1000000000000000000"},
+		{"print 1000000.0 * 1000000 * 1000000 * 1000;", "1e21"},
+		{"print 1e21; print -1.5e300; print num(str(1e21)) == 1e21;", "1e21\n-1.5e300\ntrue"},
+		{"print 1 / 10000000;", "1e-7"},
+		{"print 9223372036854775808; print -9223372036854775808;", "9223372036854776000\n-9223372036854776000"},
+		{"print 1 / 0;", "inf"},
+		{"print split(\"1,2\", \",\").len() / 4;", "0.5"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestConversionNatives(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print str(1.5) + str(nil) + str(true);`, "1.5niltrue"},
+		{`print num("2.5") + 1;`, "3.5"},
+		{`print num(" 7 ");`, "7"},
+		{`print num("abc");`, "nil"},
+		{`print int(-2.7) + int("3.9");`, "1"},
+		{`print format("%.2f", 3.14159);`, "3.14"},
+		{`print format("%05d", 42);`, "00042"},
+		{`print format("[%s] 100%%", nil);`, "[nil] 100%"},
+		{`print format("100%%");`, "100%"},
+		{`print format("%d-%d-%s", 1, 2, "three");`, "1-2-three"},
+		{`print toFixed(2.005, 1);`, "2.0"},
+		{`print toFixed(1234.5678, 2);`, "1234.57"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+
+	err := testInterpreterError(`format("%d", 1.5);`, t)
+	if err.Message != "format: %d expects an integer, got 1.5." {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+	err = testInterpreterError(`format();`, t)
+	if err.Message != "Expected at least 1 arguments but got 0." {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+	err = testInterpreterError(`int("x");`, t)
+	if err.Message != `int: invalid number "x".` {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+}
+
+func TestIOLibrary(t *testing.T) {
+	defer func() { options.FileRoot = "" }()
+
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{"writeFile(\"new.txt\", \"one\n\"); appendFile(\"new.txt\", \"two\r\n\"); print readFile(\"new.txt\");", "one\ntwo\r\n"},
+		{`print readLines("a.txt");`, "[one, two]"},
+		{`print exists("a.txt") and !exists("b.txt");`, "true"},
+		{`writeFile("/b.txt", ""); print listDir(".");`, "[a.txt, b.txt]"},
+	}
+
+	for _, test := range tests {
+		// every row starts from a root that only contains a.txt
+		options.FileRoot = t.TempDir()
+		if err := os.WriteFile(filepath.Join(options.FileRoot, "a.txt"), []byte("one\ntwo\r\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+
+	errors := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`readFile("../secret");`, `readFile: "../secret" is outside the file root.`},
+		{`readFile("missing.txt");`, "readFile: missing.txt: no such file or directory."},
+	}
+	for _, test := range errors {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestIOLibraryDanglingSymlink(t *testing.T) {
+	options.FileRoot = t.TempDir()
+	defer func() { options.FileRoot = "" }()
+	outside := filepath.Join(t.TempDir(), "pwned")
+	if err := os.Symlink(outside, filepath.Join(options.FileRoot, "evil")); err != nil {
+		t.Skip(err)
+	}
+
+	for _, input := range []string{`writeFile("evil", "x");`, `appendFile("evil", "x");`} {
+		err := testInterpreterError(input, t)
+		if !strings.Contains(err.Message, "is a dangling symbolic link.") {
+			t.Errorf("Unexpected error message %q", err.Message)
+		}
+	}
+	if _, err := os.Lstat(outside); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be created", outside)
+	}
+}
+
+func TestIOLibraryDisabled(t *testing.T) {
+	err := testInterpreterError(`print exists("a.txt");`, t)
+	if err.Message != "exists: file access is disabled." {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+}
+
+func TestInputNatives(t *testing.T) {
+	tests := []struct {
+		stdin          string
+		input          string
+		expectedOutput string
+	}{
+		{"Ada\n", `var name = input("name? "); print "hi " + name;`, "name? hi Ada"},
+		{"a\r\nb", `print readLine() + readLine(); print readLine();`, "ab\nnil"},
+		{"x\ny\nz\n", `readLine(); print readAll();`, "y\nz\n"},
+	}
+
+	defer SetOptions(Options{})
+	for _, test := range tests {
+		SetOptions(Options{Reader: strings.NewReader(test.stdin)})
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestProcessNatives(t *testing.T) {
+	t.Setenv("GOLOX_TEST_VAR", "set")
+	defer SetOptions(Options{})
+	SetOptions(Options{Args: []string{"one", "two"}})
+
+	testInterpreterOutput(`print args(); print args().len();`, "[one, two]\n2", t)
+	testInterpreterOutput(`print env("GOLOX_TEST_VAR"); print env("GOLOX_TEST_UNSET");`, "set\nnil", t)
+}
+
+func TestExit(t *testing.T) {
+	input := `
+	fun quit() {
+		while (true) {
+			exit(3);
+		}
+	}
+	print "before";
+	quit();
+	print "after";
+	`
+	s := scanner.New(input)
+	p := parser.New(s.ScanTokens())
+	statements := p.Parse()
+	resolution, _ := semantic.Resolve(statements)
+
+	out := &strings.Builder{}
+	options.Writer = out
+	err := Interpret(statements, env.New(globals), resolution)
+
+	if exit, ok := err.(ExitError); !ok || exit.Code != 3 {
+		t.Errorf("Expected ExitError with code 3. Got %v", err)
+	}
+	if out.String() != "before\n" {
+		t.Errorf("Expected only <before> to be printed. Got <%s>", out.String())
+	}
+}
+
+func TestMapBuiltin(t *testing.T) {
+	input := `
+	var m = Map();
+	m.set("b", 1);
+	m.set("a", 2);
+	m.set("b", 3);
+	print m;
+	print m.get("b") + m.len() + len(m);
+	print m.has("a") and !m.has("c") and m.get("c") == nil;
+	m.remove("b");
+	print m.keys();
+	print m.values();
+	`
+	testInterpreterOutput(input, "{b: 3, a: 2}\n7\ntrue\n[a]\n[2]", t)
+}
+
+func TestJSONLibrary(t *testing.T) {
+	tests := []struct {
+		stdin          string
+		input          string
+		expectedOutput string
+	}{
+		{`{"event": "push", "commits": [{"id": 1.5}, {"id": 2}], "ok": true, "none": null}`,
+			`var payload = jsonParse(readAll());
+			print payload.get("event");
+			print payload.get("commits").get(1).get("id");
+			print payload.get("none") == nil and payload.get("ok");
+			print jsonStringify(payload, 0);`,
+			"push\n2\ntrue\n" + `{"event":"push","commits":[{"id":1.5},{"id":2}],"ok":true,"none":null}`},
+		{`[1, "two", []]`,
+			`print jsonStringify(jsonParse(readAll()), 2);`,
+			"[\n  1,\n  \"two\",\n  []\n]"},
+		{`"<tag>"`,
+			`print jsonStringify(jsonParse(readAll()), nil);`,
+			`"<tag>"`},
+		{``,
+			`class Point { init(x, y) { this.y = y; this.x = x; } }
+			print jsonStringify(Point(1, Point(2, 3)), nil);`,
+			`{"x":1,"y":{"x":2,"y":3}}`},
+	}
+
+	defer SetOptions(Options{})
+	for _, test := range tests {
+		SetOptions(Options{Reader: strings.NewReader(test.stdin)})
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestJSONLibraryErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`var xs = List(); xs.push(xs); jsonStringify(xs, 0);`, "jsonStringify: cannot encode a cyclic structure."},
+		{`class A {} var a = A(); a.self = a; jsonStringify(a, 0);`, "jsonStringify: cannot encode a cyclic structure."},
+		{`jsonStringify(clock, 0);`, "jsonStringify: cannot encode <native/clock>."},
+		{`jsonParse("[1,");`, "jsonParse: unexpected end of JSON input."},
+		{`jsonParse("1 2");`, "jsonParse: unexpected data after the top-level value."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestRegexLibrary(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var re = regex("([a-z]+)-([0-9]+)?"); print re;`, "<native-instance Regex>"},
+		{`print regex("^a+$").test("aaa") and !regex("^a+$").test("ab");`, "true"},
+		{`print regex("([a-z]+)-([0-9]+)?").find("x abc-12 y");`, "[abc-12, abc, 12]"},
+		{`print regex("([a-z]+)-([0-9]+)?").find("abc-");`, "[abc-, abc, nil]"},
+		{`print regex("b").find("aaa");`, "nil"},
+		{`print regex("[0-9]+").findAll("a1b22c333");`, "[[1], [22], [333]]"},
+		{`print regex("([a-z])([0-9])").replace("a1 b2", "$2$1");`, "1a 2b"},
+		{`print regex(" *, *").split("a , b,c");`, "[a, b, c]"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+
+	err := testInterpreterError("\nregex(\"a(\");", t)
+	if err.Message != "regex: error parsing regexp: missing closing ): `a(`." || err.Line != 2 {
+		t.Errorf("Unexpected error %q at line %d", err.Message, err.Line)
+	}
+	err = testInterpreterError(`regex("a").replace("a", 1);`, t)
+	if err.Message != "Argument 2 of 'replace' must be a string." {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+	err = testInterpreterError(`regex("a").pattern = 1;`, t)
+	if err.Message != "Cannot set property 'pattern' on a Regex." {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestTimeLibrary(t *testing.T) {
+	defer SetOptions(Options{})
+	start := time.Date(2020, 5, 17, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var start = clock(); sleep(1500); print clock() - start;`, "1.5"},
+		{`print now();`, "1589711400"},
+		{`sleep(1500); print now();`, "1589711401.5"},
+		{`sleep(1000); print formatTime(now(), "RFC3339");`, "2020-05-17T10:30:01Z"},
+		{`print formatTime(0, "2006-01-02 15:04");`, "1970-01-01 00:00"},
+		{`print parseTime("1970-01-02", "Date");`, "86400"},
+		{`print duration("1h30m") + duration("250ms");`, "5400.25"},
+		{`print formatDuration(90.5);`, "1m30.5s"},
+	}
+
+	for _, test := range tests {
+		// a fresh clock keeps the rows independent of the sleeps of the others
+		SetOptions(Options{Clock: &fakeClock{now: start}})
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+
+	err := testInterpreterError(`parseTime("yesterday", "Date");`, t)
+	if err.Message != `parseTime: parsing time "yesterday" as "2006-01-02": cannot parse "yesterday" as "2006".` {
+		t.Errorf("Unexpected error message %q", err.Message)
+	}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		literal  string
+		expected interface{}
+	}{
+		{"7 / 2;", 3.5},
+		{"6 / 2;", 3.0},
+		{"7 % 3;", int64(1)},
+		{"-7 % 3;", int64(2)},
+		{"7 % -3;", int64(-2)},
+		{"7.5 % 2;", 1.5},
+		{"-7.5 % 2;", 0.5},
+		{"1 + 2.5;", 3.5},
+		{"2 * 1.5;", 3.0},
+		{"2 ** 62;", int64(4611686018427387904)},
+		{"2 ** -1;", 0.5},
+		{"2.0 ** 2;", 4.0},
+		{"1 == 1.0;", true},
+		{"1 < 1.5;", true},
+		{"9223372036854775807 > 9223372036854775806;", true},
+		{"-9223372036854775807 - 1;", int64(-9223372036854775808)},
+	}
+
+	for _, test := range tests {
+		testLiteral(test.literal, test.expected, t)
+	}
+}
+
+func TestIntegerErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"print 9223372036854775807 + 1;", "Integer overflow."},
+		{"print -9223372036854775807 - 2;", "Integer overflow."},
+		{"print 4294967296 * 4294967296;", "Integer overflow."},
+		{"print 2 ** 63;", "Integer overflow."},
+		{"print 1 % 0;", "Division by zero."},
+		{"print div(1, 0);", "Division by zero."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestIntegerNatives(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print 1 / 4 + 0.75;`, "1"},
+		{`print div(7, 2) + div(-7, 2) + div(7.5, 2);`, "2"},
+		{`print int(2.9) + int("-3.5") + len("abc");`, "2"},
+		{`print num("12") % 5;`, "2"},
+		{`print format("%x", 255) + format("%.1f", 2);`, "ff2.0"},
+		{`print jsonParse("[1, 1.0, 1e2]");`, "[1, 1, 100]"},
+		{`var m = Map(); m.set(1.0, "one"); print m.get(1);`, "one"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestBigNumbers(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print 123456789012345678901234567890n * 10;`, "1234567890123456789012345678900"},
+		{`print 9223372036854775807n + 1;`, "9223372036854775808"},
+		{`print 1n ** 100000000000n; print (-1d) ** 9223372036854775807; print len(str(2n ** 100000));`, "1\n-1\n30103"},
+		{`print 2n ** 100;`, "1267650600228229401496703205376"},
+		{`print 10n / 4n;`, "2.5"},
+		{`print 7n % -3n;`, "-2"},
+		{`print -5n;`, "-5"},
+		{`print 0.1d + 0.2d;`, "0.3"},
+		{`print 19.99d * 3;`, "59.97"},
+		{`print 1d / 3d;`, "0.3333333333333333333333333333"},
+		{`print -7.5d % 2d;`, "0.5"},
+		{`print 2d ** -2;`, "0.25"},
+		{`print 1n + 0.5d;`, "1.5"},
+		{`print 0.5d + 0.25;`, "0.75"},
+		{`print 1n == 1.0 and 1d == 1 and 0.5d == 0.5;`, "true"},
+		{`print 9007199254740993 == 9007199254740992.0; print 9007199254740993 > 9007199254740992.0; print 0.1d == 0.1; print 10n ** 400 < inf and -inf < -(10n ** 400);`, "false\ntrue\nfalse\ntrue"},
+		{`var m = Map(); m.set(9007199254740993, "odd"); print m.get(9007199254740992.0); print m.has(9007199254740993.0) == (9007199254740993 == 9007199254740993.0);`, "nil\ntrue"},
+		{`print 1d < 2n and 3n > 2.5;`, "true"},
+		{`print bigint("99999999999999999999") + 1;`, "100000000000000000000"},
+		{`print bigint(12.9) + bigint(-2.5d);`, "10"},
+		{`print decimal(0.1) * 3;`, "0.3"},
+		{`print decimal("2.50") + decimal(5n);`, "7.5"},
+		{`print int(12.9d) + int(5n);`, "17"},
+		{`print float(1d / 8d);`, "0.125"},
+		{`print format("%.2f|", 2.675d) + format("%d|", 10n ** 20) + format("%x", 255n);`, "2.68|100000000000000000000|ff"},
+		{`var l = List(); l.push(1n); l.push(2.50d); print jsonStringify(l, nil);`, "[1,2.5]"},
+		{`var m = Map(); m.set(100000000000000000000n, "big"); m.set(2d, "two"); print m.get(100000000000000000000n) + m.get(2); print m;`, "bigtwo\n{100000000000000000000: big, 2: two}"},
+		{`var m = Map(); m.set(2.5, "a"); m.set(1e20, "b"); m.set(2n ** 64, "c"); print m.get(2.5d) + m.get(10n ** 20) + m.get(18446744073709551616.0); print m.len();`, "abc\n3"},
+		{`var m = Map(); m.set(0.5d, "half"); m.set(0.1d, "tenth"); print m.get(0.5) + m.get(1d / 10); print m.has(10n ** 20 + 1) or m.has(1e20);`, "halftenth\nfalse"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestBigNumberErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"print 1n / 0n;", "Division by zero."},
+		{"print 1d % 0;", "Division by zero."},
+		{"print 0d ** -1;", "Division by zero."},
+		{"print (2n ** 100000000000n) > 0n;", "Number is too large."},
+		{"print 3n ** 9223372036854775808n;", "Number is too large."},
+		{"print 0.5d ** -100000000000;", "Number is too large."},
+		{`print bigint("1.5");`, `bigint: invalid integer "1.5".`},
+		{`print decimal("1/3");`, `decimal: invalid number "1/3".`},
+		{"print int(100000000000000000000n);", "int: 100000000000000000000 is out of the integer range."},
+		{`print 1n + "a";`, "Operands must be two numbers or two strings"},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print 12 & 10;`, "8"},
+		{`print 12 | 3;`, "15"},
+		{`print 12 ^ 10;`, "6"},
+		{`print ~5;`, "-6"},
+		{`print 1 << 4;`, "16"},
+		{`print -16 >> 2;`, "-4"},
+		{`print -1 >> 100;`, "-1"},
+		{`print 1 + 2 << 3;`, "24"},
+		{`print 1 | 2 == 3;`, "true"},
+		{`print 6 & 3 ^ 1;`, "3"},
+		{`print 4.0 & 5;`, "4"},
+		{`print 6d | 1;`, "7"},
+		{`print 1n << 100;`, "1267650600228229401496703205376"},
+		{`print 0n << 100000000000; print 1n >> 100000000000;`, "0\n0"},
+		{`print 255n & 15;`, "15"},
+		{`print ~0n;`, "-1"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestBitwiseErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"print 1.5 & 1;", "Operands must be integers."},
+		{`print 1 | "a";`, "Operands must be integers."},
+		{"print ~0.5;", "Operand must be an integer."},
+		{"print 1 << -1;", "Shift count must not be negative."},
+		{"print 1 << 63;", "Integer overflow."},
+		{"print (1n << 100000000000) > 0n;", "Number is too large."},
+		{"print 3 << 62;", "Integer overflow."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var x = 1; x += 2; print x; x -= 1; print x; x *= 10; print x;`, "3\n2\n20"},
+		{`var x = 20; x /= 4; print x; x **= 2; print x; x %= 4; print x;`, "5\n25\n1"},
+		{`var s = "a"; s += "b"; print s;`, "ab"},
+		{`var i = 0; print i++; print i; print ++i; print i--; print --i;`, "0\n1\n2\n2\n0"},
+		{`{ var i = 0; var j = i += 5; print i + j; }`, "10"},
+		{`fun counter() { var c = 0; fun inc() { return c++; } return inc; } var f = counter(); f(); f(); print f();`, "2"},
+		{`var a = 1; var b = 2; print (a++, b++); print a + b;`, "2\n5"},
+		{`class P { init() { this.n = 1; } }
+		  var calls = 0;
+		  var p = P();
+		  fun get() { calls = calls + 1; return p; }
+		  get().n += 5; print p.n;
+		  print get().n++; print ++get().n;
+		  print calls;`, "6\n6\n8\n3"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestCompoundAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`var s = "a"; s -= 1;`, "Operand must be a number"},
+		{`var s = "a"; s++;`, "Operands must be two numbers or two strings"},
+		{`var x = 1; x.y += 1;`, "Only instances have properties."},
+		{`class A {} var a = A(); a.missing++;`, "Undefined property 'missing'"},
+		{`var x = 9223372036854775807; x++;`, "Integer overflow."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var [a, b] = [1, 2]; print a + b;`, "3"},
+		{`var a = 1; var b = 2; [a, b] = [b, a]; print a; print b;`, "2\n1"},
+		{`{ var a = 1; var b = 2; var c = 3; [a, b, c] = [c, a, b]; print str(a) + str(b) + str(c); }`, "312"},
+		{`class Point { init(x, y) { this.x = x; this.y = y; } } var {x, y} = Point(3, 4); print x * y;`, "12"},
+		{`var m = Map(); m.set("id", 7); m.set("name", "n"); var {name, id} = m; print name + str(id);`, "n7"},
+		{`class P {} var p = P(); var q = P(); [p.a, q.b] = [1, 2]; print p.a + q.b;`, "3"},
+		{`fun pair() { var [x, y] = [1, 2]; fun f() { return x + y; } return f; } print pair()();`, "3"},
+		{`print [1, "a", [nil]];`, "[1, a, [nil]]"},
+		{`var b; var l = [b = 5, 6]; print l.len() + b;`, "7"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`var [a, b] = [1, 2, 3];`, "Expected 2 values to unpack but got 3."},
+		{`var [a, b] = "ab";`, "Only lists can be destructured with '[', got ab."},
+		{`var {x} = 1;`, "Only instances and maps can be destructured with '{', got 1."},
+		{`class P {} var {x} = P();`, "Undefined property 'x'"},
+		{`var {x} = Map();`, "Undefined key 'x'."},
+		{`var a = 1; var b = 2; [a, b] = [1];`, "Expected 2 values to unpack but got 1."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`const A = 1; print A + 1;`, "2"},
+		{`{ const B = 2; fun f() { return B * 2; } print f(); }`, "4"},
+		{`const [a, b] = [1, 2]; print a + b;`, "3"},
+		{`class P { init() { this.x = 1; } } const p = P(); p.x = 5; print p.x;`, "5"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestConstantRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		// f is resolved before B is declared, so only the runtime check applies
+		{`fun f() { B = 2; } const B = 1; f();`, "Cannot assign to constant 'B'."},
+		{`fun f() { C += 1; } const C = 1; f();`, "Cannot assign to constant 'C'."},
+		{`fun f() { [D] = [1]; } const [D] = [0]; f();`, "Cannot assign to constant 'D'."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestConstantRedeclarationAcrossInputs(t *testing.T) {
+	tests := []string{`var A = 2;`, `var A;`, `var [A] = [2];`, `fun A() {}`, `class A {}`}
+
+	options.Writer = &strings.Builder{}
+	defer ResetGlobalEnv()
+	for _, input := range tests {
+		// every REPL input is resolved on its own, so only the runtime check applies
+		GlobalEnv = env.New(globals)
+		for _, source := range []string{`const A = 1;`, input, `A = 3;`} {
+			scanner := scanner.New(source)
+			parser := parser.New(scanner.ScanTokens())
+			statements := parser.Parse()
+			resolution, _ := semantic.Resolve(statements)
+			err := Interpret(statements, GlobalEnv, resolution)
+			if source == input && (err == nil || err.(*runtimeerror.Error).Message != "Cannot redeclare constant 'A'.") {
+				t.Errorf("Expected a redeclaration error for %q. Got %v", input, err)
+			}
+		}
+		value, _ := GlobalEnv.Get(token.Token{Type: token.IDENTIFIER, Lexeme: "A"}, -1)
+		if value != int64(1) {
+			t.Errorf("Expected constant A to keep its value after %q. Got %v", input, value)
+		}
+	}
+}
+
+func TestCallArguments(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`fun f(a, b = 2, ...rest) { print str(a) + " " + str(b) + " " + str(rest); } f(1); f(1, 5); f(1, 5, 6, 7);`, "1 2 []\n1 5 []\n1 5 [6, 7]"},
+		{`fun f(a, b = 2) { return str(a) + str(b); } print f(b: 3, a: 4);`, "43"},
+		{`fun f(a, b, c) { return a + b * c; } print f(...[1, 2, 3]); print f(1, ...[2], 4);`, "7\n9"},
+		{`fun g(a, b = a * 2) { return a + b; } print g(1); print g(1, 1);`, "3\n2"},
+		{`var calls = 0; fun next() { calls++; return calls; } fun h(x = next()) { return x; } h(); h(0); print h();`, "2"},
+		{`fun f(list = []) { list.push(1); return list.len(); } f(); print f();`, "1"},
+		{`class P { init(x = 0, y = 0) { this.x = x; this.y = y; } } var p = P(y: 5); print p.x + p.y;`, "5"},
+		{`class A { m(a, ...rest) { return rest.len(); } } print A().m(1, 2, 3);`, "2"},
+		{`fun all(...xs) { return xs; } print all(); print all(...[1, 2], ...[3]);`, "[]\n[1, 2, 3]"},
+		{`print max(...[3, 7]);`, "7"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestCallArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`fun f(a, b = 1) {} f();`, "Expected 1 to 2 arguments but got 0."},
+		{`fun f(a) {} f(1, 2);`, "Expected 1 arguments but got 2."},
+		{`fun f(a, ...r) {} f();`, "Expected at least 1 arguments but got 0."},
+		{`fun f(a) {} f(b: 1);`, "'f' has no parameter named 'b'."},
+		{`fun f(a) {} f(1, a: 2);`, "Got multiple values for argument 'a'."},
+		{`fun f(a, b = 1) {} f(b: 2);`, "Missing argument 'a'."},
+		{`fun f(a, ...r) {} f(r: 2);`, "'f' has no parameter named 'r'."},
+		{`print sqrt(x: 1);`, "'sqrt' does not accept named arguments."},
+		{`class A {} A(x: 1);`, "'A' has no parameter named 'x'."},
+		{`fun f(a) {} f(...1);`, "Only lists can be spread, got 1."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestForIn(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`for (x in [1, 2, 3]) print x;`, "1\n2\n3"},
+		{`var m = Map(); m.set("a", 1); m.set("b", 2); for (k in m) print k;`, "a\nb"},
+		{`for (c in "hé") print c;`, "h\né"},
+		{`for (i in range(3)) print i;`, "0\n1\n2"},
+		{`for (i in range(2, 4)) print i;`, "2\n3"},
+		{`for (i in range(10, 0, -4)) print i;`, "10\n6\n2"},
+		{`for (i in range(0, 10, 3)) { if (i == 3) continue; if (i == 9) break; print i; }`, "0\n6"},
+		{`var xs = [1]; for (x in xs) { if (x < 3) xs.push(x + 1); print x; }`, "1\n2\n3"},
+		{`var fs = []; for (i in range(3)) { fun f() { return i; } fs.push(f); } for (f in fs) print f();`, "0\n1\n2"},
+		{`fun sum(xs) { var total = 0; for (x in xs) total += x; return total; } print sum([1, 2, 3]);`, "6"},
+		{`class It { init(n) { this.n = n; } hasNext() { return this.n > 0; } next() { this.n--; return this.n; } }
+		  class Down { iterator() { return It(3); } }
+		  for (v in Down()) print v;`, "2\n1\n0"},
+		{`print range(4);`, "range(0, 4, 1)"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestForInErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`for (x in 42) print x;`, "Cannot iterate over 42."},
+		{`class A {} for (x in A()) print x;`, "Undefined property 'iterator'"},
+		{`class A { iterator() { return 1; } } for (x in A()) print x;`, "iterator() must return an object, got 1."},
+		{`range(0, 1, 0);`, "range: step must not be zero."},
+		{`range(0, 1, 2, 3);`, "Expected 1 to 3 arguments but got 4."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`fun count(n) { var i = 0; while (i < n) { yield i; i++; } } for (x in count(3)) print x;`, "0\n1\n2"},
+		{`fun two() { yield 1; yield 2; } var g = two(); print g.hasNext(); print g.hasNext(); print g.next(); print g.next(); print g.hasNext();`, "true\ntrue\n1\n2\nfalse"},
+		{`fun fib() { var a = 0; var b = 1; while (true) { yield a; [a, b] = [b, a + b]; } } for (x in fib()) { if (x > 10) break; print x; }`, "0\n1\n1\n2\n3\n5\n8"},
+		{`fun naturals() { var i = 0; while (true) yield i++; } var g = naturals(); g.next(); g.close(); print g.hasNext();`, "false"},
+		{`fun early() { yield 1; return; yield 2; } for (x in early()) print x;`, "1"},
+		{`fun lazy() { print "started"; yield nil; } var g = lazy(); print "created"; g.next();`, "created\nstarted"},
+		{`class Pair { init(a, b) { this.a = a; this.b = b; } items() { yield this.a; yield this.b; } } for (x in Pair(3, 4).items()) print x;`, "3\n4"},
+		{`fun outer() { for (i in range(2)) { fun inner() { yield i * 10; } for (v in inner()) yield v + 1; } } for (x in outer()) print x;`, "1\n11"},
+		{`fun empty() { if (false) yield; } print empty().hasNext();`, "false"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`fun one() { yield 1; } var g = one(); g.next(); g.next();`, "Generator is exhausted."},
+		{`fun bad() { yield 1; print missing; } var g = bad(); g.next(); g.next();`, "Undefined variable 'missing'"},
+		{`var g; fun self() { g.next(); yield 1; } g = self(); g.next();`, "Generator is already running."},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+// waitForGoroutines collects garbage until at most n goroutines are left
+func waitForGoroutines(n int) int {
+	for i := 0; i < 100 && runtime.NumGoroutine() > n; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	return runtime.NumGoroutine()
+}
+
+func TestAbandonedGeneratorsStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	testInterpreterOutput(`fun gen() { yield 1; yield 2; }
+		fun abandon() { for (i in range(100)) { var g = gen(); g.next(); } }
+		abandon();
+		print "done";`, "done", t)
+	if after := waitForGoroutines(before); after > before {
+		t.Errorf("Expected the goroutines of abandoned generators to exit. Got %d, before %d", after, before)
+	}
+
+	// a loop that exits early closes its generator
+	testInterpreterOutput(`fun naturals() { var i = 0; while (true) yield i++; }
+		fun first() { for (i in range(50)) { for (n in naturals()) break; } }
+		first();
+		print "done";`, "done", t)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected the loops to close their generators. Got %d, before %d", after, before)
+	}
+
+	// the environment of these generators reaches their instances, so only
+	// closing them at the end of the script stops them
+	testInterpreterOutput(`fun abandon() { for (i in range(50)) { var g; fun gen() { yield g; yield 2; } g = gen(); g.next(); } }
+		abandon();
+		print "done";`, "done", t)
+	CloseGenerators()
+	if after := waitForGoroutines(before); after > before {
+		t.Errorf("Expected the goroutines of self-referencing generators to exit. Got %d, before %d", after, before)
+	}
+}
+
+func TestGeneratorSurvivesGCInForIn(t *testing.T) {
+	scanner := scanner.New(`fun naturals() { var n = 0; while (true) { yield n; n = n + 1; } }
+		var last;
+		for (n in naturals()) { var garbage = [n, n, n]; gc(); last = n; if (n == 100) break; }
+		print last;`)
+	parser := parser.New(scanner.ScanTokens())
+	statements := parser.Parse()
+
+	out := &strings.Builder{}
+	options.Writer = out
+	GlobalEnv = env.New(globals)
+	defer ResetGlobalEnv()
+	GlobalEnv.Define("gc", &NativeFunction{name: "gc", arity: 0, nativeCall: func([]interface{}) (interface{}, error) {
+		runtime.GC()
+		time.Sleep(time.Millisecond) // let the finalizers run
+		return nil, nil
+	}}, -1)
+	resolution, _ := semantic.Resolve(statements)
+	Interpret(statements, GlobalEnv, resolution)
+
+	if output := strings.TrimSuffix(out.String(), "\n"); output != "100" {
+		t.Errorf("Expected <100>. Got <%s>", output)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	classes := `class Point { init(x, y) { this.x = x; this.y = y; } }
+		class Point3 < Point { init(x, y, z) { super.init(x, y); this.z = z; } }
+		class Other {}
+		fun describe(v) {
+			match (v) {
+				case 1, 2 => return "small";
+				case -1 => return "minus one";
+				case "x" => return "x";
+				case Point(x: 0, y) => return "y axis " + str(y);
+				case Point(x, y) if x == y => return "diagonal " + str(x);
+				case Point(x, y) => return "point " + str(x) + " " + str(y);
+				case [] => return "empty";
+				case [a] => return "one " + str(a);
+				case [a, [b], ...rest] => return str(a) + " " + str(b) + " " + str(rest);
+				case nil => return "nothing";
+				case _ => return "other";
+			}
+		}
+		`
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print describe(2); print describe(2.0); print describe(-1); print describe("x");`, "small\nsmall\nminus one\nx"},
+		{`print describe(Point(0, 5)); print describe(Point(3, 3)); print describe(Point3(1, 2, 3));`, "y axis 5\ndiagonal 3\npoint 1 2"},
+		{`print describe(Other()); print describe(nil); print describe(true);`, "other\nnothing\nother"},
+		{`print describe([]); print describe([7]); print describe([1, [2], 3, 4]); print describe([1, 2]);`, "empty\none 7\n1 2 [3, 4]\nother"},
+		{`match (3) { case 1 => print 1; }`, ""},
+		{`for (i in range(5)) { match (i) { case 1 => continue; case 3 => break; case n => print n; } }`, "0\n2"},
+		{`var x = "outer"; match (1) { case x => print x; } print x;`, "1\nouter"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(classes+test.input, test.expectedOutput, t)
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`var NotAClass = 1; match (1) { case NotAClass(x) => print x; }`, "'NotAClass' is not a class."},
+		{`match (true) { case n if n > 1 => print n; }`, "Operand must be a number"},
+	}
+
+	for _, test := range tests {
+		err := testInterpreterError(test.input, t)
+		if err.Message != test.expectedMessage {
+			t.Errorf("Expected error %q. Got %q", test.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestDoWhileAndLabels(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`var i = 0; do { print i; i++; } while (i < 3);`, "0\n1\n2"},
+		{`do print "once"; while (false);`, "once"},
+		{`var i = 0; do { i++; if (i == 2) continue; if (i == 4) break; print i; } while (true);`, "1\n3"},
+		{`var a; outer: for (a = 0; a < 3; a = a + 1) { for (b in range(3)) { if (b == 1) continue outer; if (a == 2) break outer; print str(a) + str(b); } }`, "00\n10"},
+		{`rows: for (x in range(3)) { var j = 0; while (true) { j++; if (j > 2) continue rows; if (x == 2) break rows; print str(x) + str(j); } }`, "01\n02\n11\n12"},
+		{`var k = 0; d: do { k++; for (y in range(3)) { if (k < 3) continue d; print k; break d; } } while (true);`, "3"},
+		{`l: while (true) { match (1) { case 1 => break l; } }  print "done";`, "done"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
+
+func TestOptionalChainingAndCoalescing(t *testing.T) {
+	node := `class Node { init(v, next) { this.v = v; this.next = next; } name() { return "node" + str(this.v); } }
+		var list = Node(1, Node(2, nil));
+		`
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print list?.next?.v; print list.next.next?.v;`, "2\nnil"},
+		{`print list.next.next?.next.v; print list.next.next?.name();`, "nil\nnil"},
+		{`print list?.name(); print "abc"?.len();`, "node1\n3"},
+		{`var x = nil; print x ?? "default"; print 0 ?? 1; print false ?? 1; print nil ?? nil ?? 3;`, "default\n0\nfalse\n3"},
+		{`var x = nil; print x?.a ?? "none";`, "none"},
+		{`var calls = 0; fun f() { calls++; return 1; } print 5 ?? f(); print nil ?? f(); print calls;`, "5\n1\n1"},
+		{`print 1 ?? 2 ? "a" : "b"; print nil ?? false ? "a" : "b";`, "a\nb"},
+		{`var calls = 0; fun f() { calls++; return 1; } var x = nil; print x?.m(f()); print calls;`, "nil\n0"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(node+test.input, test.expectedOutput, t)
+	}
+
+	err := testInterpreterError(`var x = 1; x?.a;`, t)
+	if err.Message != "Only instances have properties." {
+		t.Errorf("Expected error %q. Got %q", "Only instances have properties.", err.Message)
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`print "a\tb";`, "a\tb"},
+		{`print r"C:\new";`, `C:\new`},
+		{`print regex("\d+").find("ab12");`, "[12]"},
+		{"var sql = \"\"\"\n    SELECT *\n      FROM t;\n    \"\"\";\nprint sql;", "SELECT *\n  FROM t;"},
+	}
+
+	for _, test := range tests {
+		testInterpreterOutput(test.input, test.expectedOutput, t)
+	}
+}
@@ -0,0 +1,145 @@
+package interpreter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sandboxPath resolves a Lox path under options.FileRoot. Absolute paths are
+// taken relative to the root, and paths that leave the root, directly or
+// through a symbolic link, are rejected.
+func sandboxPath(native string, path string) (string, error) {
+	if options.FileRoot == "" {
+		return "", fmt.Errorf("%s: file access is disabled.", native)
+	}
+	root, err := filepath.Abs(options.FileRoot)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", native, err)
+	}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+
+	full := filepath.Join(root, filepath.FromSlash(path))
+	real, err := resolveExisting(full)
+	if err != nil {
+		return "", fmt.Errorf("%s: %q is a dangling symbolic link.", native, path)
+	}
+	for _, p := range []string{full, real} {
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("%s: %q is outside the file root.", native, path)
+		}
+	}
+	return full, nil
+}
+
+// resolveExisting resolves the symbolic links of the deepest existing ancestor
+// of path and appends the components that do not exist yet. A dangling link
+// is an error, since creating the file would follow it.
+func resolveExisting(path string) (string, error) {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real, nil
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("dangling symbolic link")
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	real, err := resolveExisting(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(real, filepath.Base(path)), nil
+}
+
+// ioError reports an os error using the Lox path, so that the location of
+// the file root is not revealed to scripts
+func ioError(native string, path string, err error) error {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	return fmt.Errorf("%s: %s: %v.", native, path, err)
+}
+
+func defineIONative(name string, arity int, call func(full string, path string, args []interface{}) (interface{}, error)) {
+	defineNative(name, arity, func(args []interface{}) (interface{}, error) {
+		path, err := stringArgument(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		full, err := sandboxPath(name, path)
+		if err != nil {
+			return nil, err
+		}
+		return call(full, path, args)
+	})
+}
+
+func writeNative(name string, flag int) func(string, string, []interface{}) (interface{}, error) {
+	return func(full string, path string, args []interface{}) (interface{}, error) {
+		content, err := stringArgument(name, args, 1)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(full, flag, 0644)
+		if err != nil {
+			return nil, ioError(name, path, err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(content); err != nil {
+			return nil, ioError(name, path, err)
+		}
+		return nil, nil
+	}
+}
+
+func init() {
+	defineIONative("readFile", 1, func(full string, path string, args []interface{}) (interface{}, error) {
+		dat, err := os.ReadFile(full)
+		if err != nil {
+			return nil, ioError("readFile", path, err)
+		}
+		return string(dat), nil
+	})
+	defineIONative("writeFile", 2, writeNative("writeFile", os.O_WRONLY|os.O_CREATE|os.O_TRUNC))
+	defineIONative("appendFile", 2, writeNative("appendFile", os.O_WRONLY|os.O_CREATE|os.O_APPEND))
+	defineIONative("readLines", 1, func(full string, path string, args []interface{}) (interface{}, error) {
+		dat, err := os.ReadFile(full)
+		if err != nil {
+			return nil, ioError("readLines", path, err)
+		}
+		lines := make([]interface{}, 0)
+		for _, line := range strings.SplitAfter(string(dat), "\n") {
+			if line != "" {
+				lines = append(lines, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+			}
+		}
+		return NewList(lines), nil
+	})
+	defineIONative("exists", 1, func(full string, path string, args []interface{}) (interface{}, error) {
+		_, err := os.Stat(full)
+		if err == nil {
+			return true, nil
+		} else if os.IsNotExist(err) {
+			return false, nil
+		}
+		return nil, ioError("exists", path, err)
+	})
+	defineIONative("listDir", 1, func(full string, path string, args []interface{}) (interface{}, error) {
+		entries, err := os.ReadDir(full)
+		if err != nil {
+			return nil, ioError("listDir", path, err)
+		}
+		// entries are sorted by file name
+		names := make([]interface{}, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		return NewList(names), nil
+	})
+}
@@ -0,0 +1,151 @@
+package interpreter
+
+import (
+	"fmt"
+	"github.com/jfourkiotis/golox/runtimeerror"
+	"github.com/jfourkiotis/golox/token"
+	"math"
+	"runtime"
+)
+
+// iterator returns the next element of an iterable. done is true once the
+// iterable is exhausted.
+type iterator func() (value interface{}, done bool, err error)
+
+// Range is the lazy sequence of integers returned by range()
+type Range struct {
+	start int64
+	stop  int64
+	step  int64
+}
+
+// String pretty prints the range
+func (r *Range) String() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.start, r.stop, r.step)
+}
+
+// iterate creates an iterator over the elements of a list, the keys of a map,
+// the characters of a string, a range, a generator, or an instance whose
+// iterator() method returns an object with hasNext() and next() methods
+func iterate(iterable interface{}, keyword token.Token) (iterator, error) {
+	switch v := iterable.(type) {
+	case *List:
+		// the length is checked on every step, so that the loop sees the
+		// elements pushed by its body
+		i := 0
+		return func() (interface{}, bool, error) {
+			if i >= len(v.Elements) {
+				return nil, true, nil
+			}
+			i++
+			return v.Elements[i-1], false, nil
+		}, nil
+	case *Map:
+		keys := append([]interface{}(nil), v.Keys()...)
+		i := 0
+		return func() (interface{}, bool, error) {
+			if i >= len(keys) {
+				return nil, true, nil
+			}
+			i++
+			return keys[i-1], false, nil
+		}, nil
+	case string:
+		characters := []rune(v)
+		i := 0
+		return func() (interface{}, bool, error) {
+			if i >= len(characters) {
+				return nil, true, nil
+			}
+			i++
+			return string(characters[i-1]), false, nil
+		}, nil
+	case *Range:
+		current, exhausted := v.start, false
+		return func() (interface{}, bool, error) {
+			if exhausted || (v.step > 0 && current >= v.stop) || (v.step < 0 && current <= v.stop) {
+				return nil, true, nil
+			}
+			value := current
+			if (v.step > 0 && current > math.MaxInt64-v.step) || (v.step < 0 && current < math.MinInt64-v.step) {
+				exhausted = true
+			} else {
+				current += v.step
+			}
+			return value, false, nil
+		}, nil
+	case *NativeInstance:
+		if generator, ok := v.State.(*generatorState); ok {
+			// the loop only holds the iterator, which must keep the instance
+			// alive, or its finalizer would close the generator mid-loop
+			return func() (interface{}, bool, error) {
+				value, done, err := generator.next()
+				runtime.KeepAlive(v)
+				return value, done, err
+			}, nil
+		}
+	case *ClassInstance:
+		it, err := callMethod(v, "iterator", keyword)
+		if err != nil {
+			return nil, err
+		}
+		object, ok := it.(PropertyAccessor)
+		if !ok {
+			return nil, runtimeerror.Make(keyword, fmt.Sprintf("iterator() must return an object, got %s.", stringify(it)))
+		}
+		return func() (interface{}, bool, error) {
+			hasNext, err := callMethod(object, "hasNext", keyword)
+			if err != nil || !isTruthy(hasNext) {
+				return nil, true, err
+			}
+			value, err := callMethod(object, "next", keyword)
+			return value, false, err
+		}, nil
+	}
+	return nil, runtimeerror.Make(keyword, fmt.Sprintf("Cannot iterate over %s.", stringify(iterable)))
+}
+
+// callMethod calls a method without arguments, reporting errors at tok
+func callMethod(object PropertyAccessor, name string, tok token.Token) (interface{}, error) {
+	property, err := object.Get(token.Token{Type: token.IDENTIFIER, Lexeme: name, Line: tok.Line})
+	if err != nil {
+		return nil, err
+	}
+	method, ok := property.(Callable)
+	if !ok {
+		return nil, runtimeerror.Make(tok, fmt.Sprintf("'%s' must be a method.", name))
+	}
+	args, err := bindArguments(method, tok, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := method.Call(args)
+	if err != nil {
+		return nil, unwind(err, method, tok)
+	}
+	return result, nil
+}
+
+func init() {
+	// range(stop), range(start, stop) or range(start, stop, step)
+	defineNative("range", 1, func(args []interface{}) (interface{}, error) {
+		bounds := make([]int64, len(args))
+		for i := range args {
+			bound, err := integerArgument("range", args, i)
+			if err != nil {
+				return nil, err
+			}
+			bounds[i] = bound
+		}
+		switch len(bounds) {
+		case 1:
+			return &Range{start: 0, stop: bounds[0], step: 1}, nil
+		case 2:
+			return &Range{start: bounds[0], stop: bounds[1], step: 1}, nil
+		}
+		if bounds[2] == 0 {
+			return nil, fmt.Errorf("range: step must not be zero.")
+		}
+		return &Range{start: bounds[0], stop: bounds[1], step: bounds[2]}, nil
+	}).optional = 2
+}
@@ -0,0 +1,231 @@
+package interpreter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// decodeJSON converts the next JSON value of the decoder to a Lox value.
+// Objects become maps that keep the key order of the document.
+func decodeJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '[':
+			elements := make([]interface{}, 0)
+			for dec.More() {
+				e, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, e)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return NewList(elements), nil
+		case '{':
+			m := NewMap()
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				m.Set(key, value)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+		return nil, fmt.Errorf("unexpected %v", t)
+	case json.Number:
+		if !strings.ContainsAny(t.String(), ".eE") {
+			if integer, err := t.Int64(); err == nil {
+				return integer, nil
+			}
+		}
+		return t.Float64()
+	}
+	// string, bool or nil
+	return tok, nil
+}
+
+// jsonEncoder converts Lox values to JSON text
+type jsonEncoder struct {
+	sb     strings.Builder
+	indent string
+	// containers that are being encoded, used to detect cycles
+	visiting map[interface{}]bool
+}
+
+func (e *jsonEncoder) newline(depth int) {
+	if e.indent != "" {
+		e.sb.WriteString("\n")
+		e.sb.WriteString(strings.Repeat(e.indent, depth))
+	}
+}
+
+func (e *jsonEncoder) writeString(s string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	e.sb.WriteString(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+func (e *jsonEncoder) enter(container interface{}) error {
+	if e.visiting[container] {
+		return fmt.Errorf("jsonStringify: cannot encode a cyclic structure.")
+	}
+	e.visiting[container] = true
+	return nil
+}
+
+// writeObject writes the given keys, which must already be in their output
+// order
+func (e *jsonEncoder) writeObject(keys []string, value func(string) interface{}, depth int) error {
+	e.sb.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			e.sb.WriteString(",")
+		}
+		e.newline(depth + 1)
+		e.writeString(key)
+		e.sb.WriteString(":")
+		if e.indent != "" {
+			e.sb.WriteString(" ")
+		}
+		if err := e.encode(value(key), depth+1); err != nil {
+			return err
+		}
+	}
+	if len(keys) > 0 {
+		e.newline(depth)
+	}
+	e.sb.WriteString("}")
+	return nil
+}
+
+func (e *jsonEncoder) encode(value interface{}, depth int) error {
+	switch v := value.(type) {
+	case nil:
+		e.sb.WriteString("null")
+	case bool, int64, *big.Int, *big.Rat:
+		e.sb.WriteString(stringify(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("jsonStringify: cannot encode %s.", stringify(v))
+		}
+		e.sb.WriteString(formatNumber(v))
+	case string:
+		e.writeString(v)
+	case *List:
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		defer delete(e.visiting, v)
+		e.sb.WriteString("[")
+		for i, element := range v.Elements {
+			if i > 0 {
+				e.sb.WriteString(",")
+			}
+			e.newline(depth + 1)
+			if err := e.encode(element, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(v.Elements) > 0 {
+			e.newline(depth)
+		}
+		e.sb.WriteString("]")
+	case *Map:
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		defer delete(e.visiting, v)
+		keys := make([]string, v.Len())
+		for i, k := range v.Keys() {
+			key, ok := k.(string)
+			if !ok {
+				return fmt.Errorf("jsonStringify: object keys must be strings, got %s.", stringify(k))
+			}
+			keys[i] = key
+		}
+		return e.writeObject(keys, func(key string) interface{} {
+			value, _ := v.Get(key)
+			return value
+		}, depth)
+	case *ClassInstance:
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		defer delete(e.visiting, v)
+		keys := make([]string, 0, len(v.fields))
+		for key := range v.fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return e.writeObject(keys, func(key string) interface{} {
+			return v.fields[key]
+		}, depth)
+	default:
+		return fmt.Errorf("jsonStringify: cannot encode %s.", stringify(v))
+	}
+	return nil
+}
+
+func init() {
+	defineNative("jsonParse", 1, func(args []interface{}) (interface{}, error) {
+		text, err := stringArgument("jsonParse", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.UseNumber()
+		value, err := decodeJSON(dec)
+		if err == nil {
+			// there must be nothing after the value
+			if _, err2 := dec.Token(); err2 != io.EOF {
+				err = fmt.Errorf("unexpected data after the top-level value")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jsonParse: %v.", err)
+		}
+		return value, nil
+	})
+	// jsonStringify(value, indent) indents nested values by the given number
+	// of spaces, or produces compact output when indent is 0 or nil
+	defineNative("jsonStringify", 2, func(args []interface{}) (interface{}, error) {
+		e := &jsonEncoder{visiting: make(map[interface{}]bool)}
+		if args[1] != nil {
+			indent, err := integerArgument("jsonStringify", args, 1)
+			if err != nil {
+				return nil, err
+			}
+			if indent < 0 || indent > 10 {
+				return nil, fmt.Errorf("jsonStringify: indent must be in [0, 10], got %d.", indent)
+			}
+			e.indent = strings.Repeat(" ", int(indent))
+		}
+		if err := e.encode(args[0], 0); err != nil {
+			return nil, err
+		}
+		return e.sb.String(), nil
+	})
+}
@@ -0,0 +1,82 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// List is the builtin Lox list
+type List struct {
+	Elements []interface{}
+}
+
+// NewList creates a list holding the given elements
+func NewList(elements []interface{}) *List {
+	return &List{Elements: elements}
+}
+
+// String pretty prints the list
+func (l *List) String() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, e := range l.Elements {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(stringify(e))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// index validates a Lox index argument against the list bounds
+func (l *List) index(name string, args []interface{}, i int) (int, error) {
+	index, err := integerArgument(name, args, i)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 || index >= int64(len(l.Elements)) {
+		return 0, fmt.Errorf("List index %d out of range.", index)
+	}
+	return int(index), nil
+}
+
+var listClass = NewNativeClass("List")
+
+func init() {
+	defineNative("List", 0, func(args []interface{}) (interface{}, error) {
+		return NewList(make([]interface{}, 0)), nil
+	})
+
+	listClass.Methods["get"] = &NativeFunction{name: "get", arity: 2, nativeCall: func(args []interface{}) (interface{}, error) {
+		list := args[0].(*List)
+		index, err := list.index("get", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return list.Elements[index], nil
+	}}
+	listClass.Methods["set"] = &NativeFunction{name: "set", arity: 3, nativeCall: func(args []interface{}) (interface{}, error) {
+		list := args[0].(*List)
+		index, err := list.index("set", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		list.Elements[index] = args[2]
+		return args[2], nil
+	}}
+	listClass.Methods["push"] = &NativeFunction{name: "push", arity: 2, nativeCall: func(args []interface{}) (interface{}, error) {
+		list := args[0].(*List)
+		list.Elements = append(list.Elements, args[1])
+		return nil, nil
+	}}
+	listClass.Methods["pop"] = &NativeFunction{name: "pop", arity: 1, nativeCall: func(args []interface{}) (interface{}, error) {
+		list := args[0].(*List)
+		if len(list.Elements) == 0 {
+			return nil, fmt.Errorf("Cannot pop from an empty list.")
+		}
+		last := list.Elements[len(list.Elements)-1]
+		list.Elements = list.Elements[:len(list.Elements)-1]
+		return last, nil
+	}}
+}
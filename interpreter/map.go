@@ -0,0 +1,155 @@
+package interpreter
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Map is the builtin Lox map. Keys keep their insertion order.
+type Map struct {
+	values map[interface{}]interface{}
+	keys   []interface{}
+}
+
+// NewMap creates an empty map
+func NewMap() *Map {
+	return &Map{values: make(map[interface{}]interface{}), keys: make([]interface{}, 0)}
+}
+
+// bigKey is the key of a bigint or a decimal that is not exactly a float64
+type bigKey struct {
+	text string
+}
+
+// mapKey normalizes numeric keys the way == compares them, so that 1, 1.0, 1n
+// and 1d are the same key, and so are 2.5 and 2.5d or 1e20 and 10n ** 20.
+// Integers that fit in an int64 become int64 keys, other numbers that are
+// exactly a float64 become float64 keys, and the rest are keyed by their
+// exact text.
+func mapKey(key interface{}) interface{} {
+	switch v := key.(type) {
+	case float64:
+		if integer, ok := floatToInteger(v); ok {
+			return integer
+		}
+	case *big.Int:
+		if v.IsInt64() {
+			return v.Int64()
+		}
+		if f, accuracy := new(big.Float).SetInt(v).Float64(); accuracy == big.Exact {
+			return f
+		}
+		return bigKey{v.String()}
+	case *big.Rat:
+		if v.IsInt() {
+			return mapKey(v.Num())
+		}
+		if f, exact := v.Float64(); exact {
+			return f
+		}
+		return bigKey{formatDecimal(v)}
+	}
+	return key
+}
+
+// Get returns the value of a key and whether the key is present
+func (m *Map) Get(key interface{}) (interface{}, bool) {
+	v, prs := m.values[mapKey(key)]
+	return v, prs
+}
+
+// Set binds a key to a value
+func (m *Map) Set(key interface{}, value interface{}) {
+	normalized := mapKey(key)
+	if _, prs := m.values[normalized]; !prs {
+		if _, ok := normalized.(bigKey); ok {
+			m.keys = append(m.keys, key)
+		} else {
+			m.keys = append(m.keys, normalized)
+		}
+	}
+	m.values[normalized] = value
+}
+
+// Remove deletes a key and reports whether it was present
+func (m *Map) Remove(key interface{}) bool {
+	key = mapKey(key)
+	if _, prs := m.values[key]; !prs {
+		return false
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if mapKey(k) == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Keys returns the keys in insertion order
+func (m *Map) Keys() []interface{} {
+	return m.keys
+}
+
+// Len returns the number of entries
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+// String pretty prints the map
+func (m *Map) String() string {
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, k := range m.keys {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(stringify(k))
+		sb.WriteString(": ")
+		sb.WriteString(stringify(m.values[mapKey(k)]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+var mapClass = NewNativeClass("Map")
+
+func defineMapMethod(name string, arity int, call func(m *Map, args []interface{}) (interface{}, error)) {
+	mapClass.Methods[name] = &NativeFunction{name: name, arity: arity, nativeCall: func(args []interface{}) (interface{}, error) {
+		return call(args[0].(*Map), args[1:])
+	}}
+}
+
+func init() {
+	defineNative("Map", 0, func(args []interface{}) (interface{}, error) {
+		return NewMap(), nil
+	})
+
+	// get returns nil for missing keys
+	defineMapMethod("get", 2, func(m *Map, args []interface{}) (interface{}, error) {
+		v, _ := m.Get(args[0])
+		return v, nil
+	})
+	defineMapMethod("set", 3, func(m *Map, args []interface{}) (interface{}, error) {
+		m.Set(args[0], args[1])
+		return args[1], nil
+	})
+	defineMapMethod("has", 2, func(m *Map, args []interface{}) (interface{}, error) {
+		_, prs := m.Get(args[0])
+		return prs, nil
+	})
+	defineMapMethod("remove", 2, func(m *Map, args []interface{}) (interface{}, error) {
+		return m.Remove(args[0]), nil
+	})
+	defineMapMethod("keys", 1, func(m *Map, args []interface{}) (interface{}, error) {
+		return NewList(append([]interface{}{}, m.keys...)), nil
+	})
+	defineMapMethod("values", 1, func(m *Map, args []interface{}) (interface{}, error) {
+		values := make([]interface{}, len(m.keys))
+		for i, k := range m.keys {
+			values[i] = m.values[mapKey(k)]
+		}
+		return NewList(values), nil
+	})
+}
@@ -0,0 +1,110 @@
+package interpreter
+
+import (
+	"fmt"
+	"github.com/jfourkiotis/golox/ast"
+	"github.com/jfourkiotis/golox/env"
+	"github.com/jfourkiotis/golox/runtimeerror"
+	"github.com/jfourkiotis/golox/semantic"
+)
+
+// match executes the body of the first case that matches the value of the
+// match statement. Nothing happens if no case matches.
+func match(n *ast.Match, environment *env.Environment, res semantic.Resolution) (interface{}, error) {
+	value, err := Eval(n.Value, environment, res)
+	if err != nil {
+		return nil, err
+	}
+	for _, matchCase := range n.Cases {
+		caseEnvironment := env.NewSized(environment, matchCase.EnvSize)
+		matched := false
+		for _, pattern := range matchCase.Patterns {
+			if matched, err = matchPattern(pattern, value, caseEnvironment, res); err != nil {
+				return nil, err
+			} else if matched {
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if matchCase.Guard != nil {
+			guard, err := Eval(matchCase.Guard, caseEnvironment, res)
+			if err != nil {
+				return nil, err
+			} else if !isTruthy(guard) {
+				continue
+			}
+		}
+		return Eval(matchCase.Body, caseEnvironment, res)
+	}
+	return nil, nil
+}
+
+// matchPattern reports whether value matches pattern, binding the variables
+// of the pattern in environment
+func matchPattern(pattern ast.Pattern, value interface{}, environment *env.Environment, res semantic.Resolution) (bool, error) {
+	switch p := pattern.(type) {
+	case *ast.LiteralPattern:
+		literal, err := Eval(p.Value, environment, res)
+		if err != nil {
+			return false, err
+		}
+		return isEqual(literal, value), nil
+	case *ast.WildcardPattern:
+		return true, nil
+	case *ast.BindingPattern:
+		environment.Define(p.Name.Lexeme, value, p.EnvIndex)
+		return true, nil
+	case *ast.ListPattern:
+		list, ok := value.(*List)
+		if !ok || len(list.Elements) < len(p.Elements) || (p.Rest == nil && len(list.Elements) != len(p.Elements)) {
+			return false, nil
+		}
+		for i, element := range p.Elements {
+			if matched, err := matchPattern(element, list.Elements[i], environment, res); err != nil || !matched {
+				return false, err
+			}
+		}
+		if p.Rest != nil {
+			rest := append([]interface{}{}, list.Elements[len(p.Elements):]...)
+			return matchPattern(p.Rest, NewList(rest), environment, res)
+		}
+		return true, nil
+	case *ast.ClassPattern:
+		classValue, err := Eval(p.Class, environment, res)
+		if err != nil {
+			return false, err
+		}
+		class, ok := classValue.(*Class)
+		if !ok {
+			return false, runtimeerror.Make(p.Class.Name, fmt.Sprintf("'%s' is not a class.", p.Class.Name.Lexeme))
+		}
+		instance, ok := value.(*ClassInstance)
+		if !ok || !isInstanceOf(instance, class) {
+			return false, nil
+		}
+		for _, field := range p.Fields {
+			fieldValue, prs := instance.fields[field.Name.Lexeme]
+			if !prs {
+				return false, nil
+			}
+			if matched, err := matchPattern(field.Value, fieldValue, environment, res); err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	panic(fmt.Sprintf("Unexpected pattern type %T\n", pattern))
+}
+
+// isInstanceOf is true if the class of instance is class or one of its
+// subclasses
+func isInstanceOf(instance *ClassInstance, class *Class) bool {
+	for c := instance.Class; c != nil; c = c.SuperClass {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
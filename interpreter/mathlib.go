@@ -0,0 +1,138 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// random is the generator behind random() and randomInt(). It is reseeded
+// by seed(n) so that scripts can reproduce a sequence.
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func init() {
+	unary := map[string]func(float64) float64{
+		"sqrt":  math.Sqrt,
+		"abs":   math.Abs,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"trunc": math.Trunc,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"log":   math.Log,
+		"exp":   math.Exp,
+	}
+	for name, fn := range unary {
+		defineMathFunction(name, fn)
+	}
+
+	binary := map[string]func(float64, float64) float64{
+		"min":   math.Min,
+		"max":   math.Max,
+		"atan2": math.Atan2,
+	}
+	for name, fn := range binary {
+		defineMathFunction2(name, fn)
+	}
+
+	globals.Define("pi", math.Pi, -1)
+	globals.Define("e", math.E, -1)
+	globals.Define("inf", math.Inf(1), -1)
+	globals.Define("nan", math.NaN(), -1)
+
+	defineNative("isNaN", 1, func(args []interface{}) (interface{}, error) {
+		x, err := numberArgument("isNaN", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return math.IsNaN(x), nil
+	})
+	defineNative("seed", 1, func(args []interface{}) (interface{}, error) {
+		s, err := integerArgument("seed", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		random.Seed(s)
+		return nil, nil
+	})
+	defineNative("random", 0, func(args []interface{}) (interface{}, error) {
+		return random.Float64(), nil
+	})
+	// randomInt(lo, hi) returns an integer in [lo, hi]
+	defineNative("randomInt", 2, func(args []interface{}) (interface{}, error) {
+		lo, err := integerArgument("randomInt", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := integerArgument("randomInt", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("randomInt: empty range [%d, %d].", lo, hi)
+		}
+		// the width of the range may not fit in an int64, but always fits in
+		// a uint64 (lo = MinInt64, hi = MaxInt64 covers all of them)
+		width := uint64(hi) - uint64(lo)
+		if width < math.MaxInt64 {
+			return lo + random.Int63n(int64(width)+1), nil
+		}
+		for {
+			// rejection sampling over the 64-bit values
+			if n := random.Uint64(); width == math.MaxUint64 || n <= width {
+				return int64(uint64(lo) + n), nil
+			}
+		}
+	})
+	// div is the floor division; it keeps integers exact
+	defineNative("div", 2, func(args []interface{}) (interface{}, error) {
+		lhs, lok := args[0].(int64)
+		rhs, rok := args[1].(int64)
+		if lok && rok {
+			if rhs == 0 {
+				return nil, fmt.Errorf(divisionByZero)
+			}
+			quotient, overflow := floorDiv(lhs, rhs)
+			if overflow {
+				return nil, fmt.Errorf(integerOverflow)
+			}
+			return quotient, nil
+		}
+		x, err := numberArgument("div", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		y, err := numberArgument("div", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return math.Floor(x / y), nil
+	})
+}
+
+func defineMathFunction(name string, fn func(float64) float64) {
+	defineNative(name, 1, func(args []interface{}) (interface{}, error) {
+		x, err := numberArgument(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return fn(x), nil
+	})
+}
+
+func defineMathFunction2(name string, fn func(float64, float64) float64) {
+	defineNative(name, 2, func(args []interface{}) (interface{}, error) {
+		x, err := numberArgument(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		y, err := numberArgument(name, args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(x, y), nil
+	})
+}
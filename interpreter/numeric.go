@@ -0,0 +1,337 @@
+package interpreter
+
+import (
+	"github.com/jfourkiotis/golox/runtimeerror"
+	"github.com/jfourkiotis/golox/token"
+	"math"
+	"math/big"
+)
+
+const (
+	integerOverflow = "Integer overflow."
+	divisionByZero  = "Division by zero."
+)
+
+// Lox integers are int64 and floats are float64. Integer operands produce
+// integers, except for '/' which always divides exactly. Mixing an integer
+// with a float promotes the integer to a float. See bignum.go for the
+// arbitrary-precision numbers.
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case int64, float64, *big.Int, *big.Rat:
+		return true
+	}
+	return false
+}
+
+// toFloat converts a number to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// floatToInteger converts an integral float to an int64 if it is in range
+func floatToInteger(number float64) (int64, bool) {
+	if number != math.Trunc(number) || number < math.MinInt64 || number >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(number), true
+}
+
+// arithmetic evaluates the binary operators - + * / % and **
+func arithmetic(operator token.Token, left interface{}, right interface{}) (interface{}, error) {
+	if err := checkNumberOperand(operator, left, operandMustBeANumber); err != nil {
+		return nil, err
+	}
+	if err := checkNumberOperand(operator, right, operandMustBeANumber); err != nil {
+		return nil, err
+	}
+	rank := numberRank(left)
+	if r := numberRank(right); r > rank {
+		rank = r
+	}
+	switch rank {
+	case rankInteger:
+		return integerArithmetic(operator, left.(int64), right.(int64))
+	case rankBigInt:
+		return bigIntArithmetic(operator, toBigInt(left), toBigInt(right))
+	case rankDecimal:
+		return decimalArithmetic(operator, toDecimal(left), toDecimal(right))
+	}
+	lhs, _ := toFloat(left)
+	rhs, _ := toFloat(right)
+	return floatArithmetic(operator, lhs, rhs), nil
+}
+
+func integerArithmetic(operator token.Token, lhs int64, rhs int64) (interface{}, error) {
+	var result int64
+	overflow := false
+	switch operator.Type {
+	case token.PLUS:
+		result = lhs + rhs
+		overflow = (lhs^result)&(rhs^result) < 0
+	case token.MINUS:
+		result = lhs - rhs
+		overflow = (lhs^rhs)&(lhs^result) < 0
+	case token.STAR:
+		result, overflow = multiply(lhs, rhs)
+	case token.SLASH:
+		return float64(lhs) / float64(rhs), nil
+	case token.PERCENT:
+		if rhs == 0 {
+			return nil, runtimeerror.Make(operator, divisionByZero)
+		}
+		return floorMod(lhs, rhs), nil
+	case token.POWER:
+		if rhs < 0 {
+			return math.Pow(float64(lhs), float64(rhs)), nil
+		}
+		result, overflow = power(lhs, rhs)
+	}
+	if overflow {
+		return nil, runtimeerror.Make(operator, integerOverflow)
+	}
+	return result, nil
+}
+
+func floatArithmetic(operator token.Token, lhs float64, rhs float64) float64 {
+	switch operator.Type {
+	case token.PLUS:
+		return lhs + rhs
+	case token.MINUS:
+		return lhs - rhs
+	case token.STAR:
+		return lhs * rhs
+	case token.SLASH:
+		return lhs / rhs
+	case token.PERCENT:
+		mod := math.Mod(lhs, rhs)
+		if mod != 0 && (mod < 0) != (rhs < 0) {
+			mod += rhs
+		}
+		return mod
+	}
+	return math.Pow(lhs, rhs)
+}
+
+// multiply returns the product of two integers and whether it overflowed
+func multiply(lhs int64, rhs int64) (int64, bool) {
+	if lhs == 0 || rhs == 0 {
+		return 0, false
+	}
+	result := lhs * rhs
+	overflow := result/rhs != lhs || (lhs == -1 && rhs == math.MinInt64) || (rhs == -1 && lhs == math.MinInt64)
+	return result, overflow
+}
+
+// power computes base**exp for exp >= 0 by repeated squaring
+func power(base int64, exp int64) (int64, bool) {
+	result := int64(1)
+	for exp > 0 {
+		var overflow bool
+		if exp&1 == 1 {
+			if result, overflow = multiply(result, base); overflow {
+				return 0, true
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, overflow = multiply(base, base); overflow {
+				return 0, true
+			}
+		}
+	}
+	return result, false
+}
+
+// floorMod returns the remainder of the floor division; its sign follows the
+// divisor, so that a == floorDiv(a, b) * b + floorMod(a, b)
+func floorMod(lhs int64, rhs int64) int64 {
+	if rhs == -1 {
+		return 0 // avoids the overflow of MinInt64 % -1
+	}
+	mod := lhs % rhs
+	if mod != 0 && (mod < 0) != (rhs < 0) {
+		mod += rhs
+	}
+	return mod
+}
+
+// floorDiv returns the quotient rounded towards negative infinity
+func floorDiv(lhs int64, rhs int64) (int64, bool) {
+	if lhs == math.MinInt64 && rhs == -1 {
+		return 0, true
+	}
+	quotient := lhs / rhs
+	if lhs%rhs != 0 && (lhs < 0) != (rhs < 0) {
+		quotient--
+	}
+	return quotient, false
+}
+
+// negate evaluates the unary '-' operator
+func negate(operator token.Token, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, runtimeerror.Make(operator, integerOverflow)
+		}
+		return -v, nil
+	case float64:
+		return -v, nil
+	case *big.Int:
+		return new(big.Int).Neg(v), nil
+	case *big.Rat:
+		return new(big.Rat).Neg(v), nil
+	}
+	return nil, runtimeerror.Make(operator, operandMustBeANumber)
+}
+
+// compare evaluates the operators > >= < <=
+func compare(operator token.Token, left interface{}, right interface{}) (interface{}, error) {
+	if err := checkNumberOperand(operator, left, operandMustBeANumber); err != nil {
+		return nil, err
+	}
+	if err := checkNumberOperand(operator, right, operandMustBeANumber); err != nil {
+		return nil, err
+	}
+	cmp, ok := compareNumbers(left, right)
+	if !ok {
+		return false, nil // NaN
+	}
+	switch operator.Type {
+	case token.GREATER:
+		return cmp > 0, nil
+	case token.GREATEREQUAL:
+		return cmp >= 0, nil
+	case token.LESS:
+		return cmp < 0, nil
+	}
+	return cmp <= 0, nil
+}
+
+// numbersEqual compares two numbers by value, so that 1 == 1.0 == 1n == 1d
+func numbersEqual(left interface{}, right interface{}) bool {
+	cmp, ok := compareNumbers(left, right)
+	return ok && cmp == 0
+}
+
+const (
+	operandsMustBeIntegers = "Operands must be integers."
+	operandMustBeAnInteger = "Operand must be an integer."
+	negativeShiftCount     = "Shift count must not be negative."
+)
+
+// integerOperand converts an operand of a bitwise operator to an int64 or a
+// bigint. Integral floats and decimals are accepted.
+func integerOperand(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case int64, *big.Int:
+		return v, true
+	case float64:
+		return floatToInteger(v)
+	case *big.Rat:
+		if !v.IsInt() {
+			return nil, false
+		}
+		if v.Num().IsInt64() {
+			return v.Num().Int64(), true
+		}
+		return new(big.Int).Set(v.Num()), true
+	}
+	return nil, false
+}
+
+// bitwise evaluates the binary operators & | ^ << and >> on integers. The
+// result is a bigint if either operand is one; shifting an int64 out of
+// range is an overflow.
+func bitwise(operator token.Token, left interface{}, right interface{}) (interface{}, error) {
+	left, lok := integerOperand(left)
+	right, rok := integerOperand(right)
+	if !lok || !rok {
+		return nil, runtimeerror.Make(operator, operandsMustBeIntegers)
+	}
+	if operator.Type == token.LESSLESS || operator.Type == token.GREATERGREATER {
+		return shift(operator, left, right)
+	}
+	lhs, lok := left.(int64)
+	rhs, rok := right.(int64)
+	if lok && rok {
+		switch operator.Type {
+		case token.AMPERSAND:
+			return lhs & rhs, nil
+		case token.PIPE:
+			return lhs | rhs, nil
+		}
+		return lhs ^ rhs, nil
+	}
+	l, r := toBigInt(left), toBigInt(right)
+	switch operator.Type {
+	case token.AMPERSAND:
+		return new(big.Int).And(l, r), nil
+	case token.PIPE:
+		return new(big.Int).Or(l, r), nil
+	}
+	return new(big.Int).Xor(l, r), nil
+}
+
+// shift evaluates << and >>; '>>' is an arithmetic shift
+func shift(operator token.Token, left interface{}, right interface{}) (interface{}, error) {
+	count, ok := right.(int64)
+	if !ok {
+		if right.(*big.Int).Sign() < 0 {
+			return nil, runtimeerror.Make(operator, negativeShiftCount)
+		}
+		return nil, runtimeerror.Make(operator, integerOverflow)
+	}
+	if count < 0 {
+		return nil, runtimeerror.Make(operator, negativeShiftCount)
+	}
+	if value, ok := left.(*big.Int); ok {
+		if operator.Type == token.LESSLESS {
+			if value.Sign() != 0 && count > maxBigBits-int64(value.BitLen()) {
+				return nil, runtimeerror.Make(operator, numberTooLarge)
+			}
+			return new(big.Int).Lsh(value, uint(count)), nil
+		}
+		return new(big.Int).Rsh(value, uint(count)), nil
+	}
+	value := left.(int64)
+	if operator.Type == token.GREATERGREATER {
+		if count > 63 {
+			count = 63
+		}
+		return value >> uint(count), nil
+	}
+	if value == 0 {
+		return value, nil
+	}
+	if count > 63 || (value<<uint(count))>>uint(count) != value {
+		return nil, runtimeerror.Make(operator, integerOverflow)
+	}
+	return value << uint(count), nil
+}
+
+// complement evaluates the unary '~' operator
+func complement(operator token.Token, value interface{}) (interface{}, error) {
+	value, ok := integerOperand(value)
+	if !ok {
+		return nil, runtimeerror.Make(operator, operandMustBeAnInteger)
+	}
+	if bigint, ok := value.(*big.Int); ok {
+		return new(big.Int).Not(bigint), nil
+	}
+	return ^value.(int64), nil
+}
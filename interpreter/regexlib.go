@@ -0,0 +1,82 @@
+package interpreter
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var regexClass = NewNativeClass("Regex")
+
+// defineRegexMethod defines a method of compiled regular expressions that
+// takes the subject string as its first argument. args excludes the receiver.
+func defineRegexMethod(name string, arity int, call func(re *regexp.Regexp, s string, args []interface{}) (interface{}, error)) {
+	regexClass.Methods[name] = &NativeFunction{name: name, arity: arity, nativeCall: func(args []interface{}) (interface{}, error) {
+		re := args[0].(*NativeInstance).State.(*regexp.Regexp)
+		s, err := stringArgument(name, args[1:], 0)
+		if err != nil {
+			return nil, err
+		}
+		return call(re, s, args[1:])
+	}}
+}
+
+// matchList returns the match followed by its capture groups. Groups that
+// did not participate in the match are nil.
+func matchList(s string, indices []int) *List {
+	elements := make([]interface{}, len(indices)/2)
+	for i := range elements {
+		if indices[2*i] >= 0 {
+			elements[i] = s[indices[2*i]:indices[2*i+1]]
+		}
+	}
+	return NewList(elements)
+}
+
+func init() {
+	defineNative("regex", 1, func(args []interface{}) (interface{}, error) {
+		pattern, err := stringArgument("regex", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("regex: %v.", err)
+		}
+		return NewNativeInstance(regexClass, re), nil
+	})
+
+	defineRegexMethod("test", 2, func(re *regexp.Regexp, s string, args []interface{}) (interface{}, error) {
+		return re.MatchString(s), nil
+	})
+	// find returns nil if there is no match
+	defineRegexMethod("find", 2, func(re *regexp.Regexp, s string, args []interface{}) (interface{}, error) {
+		indices := re.FindStringSubmatchIndex(s)
+		if indices == nil {
+			return nil, nil
+		}
+		return matchList(s, indices), nil
+	})
+	defineRegexMethod("findAll", 2, func(re *regexp.Regexp, s string, args []interface{}) (interface{}, error) {
+		matches := make([]interface{}, 0)
+		for _, indices := range re.FindAllStringSubmatchIndex(s, -1) {
+			matches = append(matches, matchList(s, indices))
+		}
+		return NewList(matches), nil
+	})
+	// replace expands $1, ${name} etc. in the replacement
+	defineRegexMethod("replace", 3, func(re *regexp.Regexp, s string, args []interface{}) (interface{}, error) {
+		replacement, err := stringArgument("replace", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return re.ReplaceAllString(s, replacement), nil
+	})
+	defineRegexMethod("split", 2, func(re *regexp.Regexp, s string, args []interface{}) (interface{}, error) {
+		parts := re.Split(s, -1)
+		elements := make([]interface{}, len(parts))
+		for i, part := range parts {
+			elements[i] = part
+		}
+		return NewList(elements), nil
+	})
+}
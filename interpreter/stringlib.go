@@ -0,0 +1,176 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var stringClass = NewNativeClass("String")
+
+// maxStringLength bounds the strings built by repeat(), in bytes
+const maxStringLength = 1 << 30
+
+// defineStringFunction defines a global native function whose first argument
+// is a string. The function is also a method of every string value, so
+// upper(s) and s.upper() are equivalent.
+func defineStringFunction(name string, arity int, call loxCallable) {
+	function := defineNative(name, arity, func(args []interface{}) (interface{}, error) {
+		if _, err := stringArgument(name, args, 0); err != nil {
+			return nil, err
+		}
+		return call(args)
+	})
+	stringClass.Methods[name] = function
+}
+
+func init() {
+	// len works on every sized builtin value
+	length := defineNative("len", 1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case string:
+			return int64(utf8.RuneCountInString(v)), nil
+		case *List:
+			return int64(len(v.Elements)), nil
+		case *Map:
+			return int64(v.Len()), nil
+		}
+		return nil, fmt.Errorf("Argument 1 of 'len' must be a string, a list or a map.")
+	})
+	stringClass.Methods["len"] = length
+	listClass.Methods["len"] = length
+	mapClass.Methods["len"] = length
+
+	// substr(s, start, end) returns the characters in [start, end)
+	defineStringFunction("substr", 3, func(args []interface{}) (interface{}, error) {
+		runes := []rune(args[0].(string))
+		start, err := integerArgument("substr", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		end, err := integerArgument("substr", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		if start < 0 || end > int64(len(runes)) || start > end {
+			return nil, fmt.Errorf("Substring range [%d, %d) out of bounds.", start, end)
+		}
+		return string(runes[start:end]), nil
+	})
+	defineStringFunction("indexOf", 2, func(args []interface{}) (interface{}, error) {
+		s := args[0].(string)
+		sub, err := stringArgument("indexOf", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		index := strings.Index(s, sub)
+		if index < 0 {
+			return int64(-1), nil
+		}
+		return int64(utf8.RuneCountInString(s[:index])), nil
+	})
+	defineStringFunction("split", 2, func(args []interface{}) (interface{}, error) {
+		sep, err := stringArgument("split", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(args[0].(string), sep)
+		elements := make([]interface{}, len(parts))
+		for i, part := range parts {
+			elements[i] = part
+		}
+		return NewList(elements), nil
+	})
+	join := defineNative("join", 2, func(args []interface{}) (interface{}, error) {
+		list, err := listArgument("join", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		sep, err := stringArgument("join", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(list.Elements))
+		for i, e := range list.Elements {
+			parts[i] = stringify(e)
+		}
+		return strings.Join(parts, sep), nil
+	})
+	listClass.Methods["join"] = join
+
+	defineStringFunction("upper", 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	})
+	defineStringFunction("lower", 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToLower(args[0].(string)), nil
+	})
+	defineStringFunction("trim", 1, func(args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(args[0].(string)), nil
+	})
+	defineStringFunction("replace", 3, func(args []interface{}) (interface{}, error) {
+		old, err := stringArgument("replace", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		replacement, err := stringArgument("replace", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ReplaceAll(args[0].(string), old, replacement), nil
+	})
+	defineStringFunction("startsWith", 2, func(args []interface{}) (interface{}, error) {
+		prefix, err := stringArgument("startsWith", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(args[0].(string), prefix), nil
+	})
+	defineStringFunction("endsWith", 2, func(args []interface{}) (interface{}, error) {
+		suffix, err := stringArgument("endsWith", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasSuffix(args[0].(string), suffix), nil
+	})
+	defineStringFunction("repeat", 2, func(args []interface{}) (interface{}, error) {
+		count, err := integerArgument("repeat", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		s := args[0].(string)
+		if count < 0 {
+			return nil, fmt.Errorf("repeat: negative count %d.", count)
+		} else if len(s) > 0 && count > maxStringLength/int64(len(s)) {
+			return nil, fmt.Errorf("repeat: result would exceed %d bytes.", maxStringLength)
+		}
+		return strings.Repeat(s, int(count)), nil
+	})
+	defineStringFunction("charAt", 2, func(args []interface{}) (interface{}, error) {
+		runes := []rune(args[0].(string))
+		index, err := integerArgument("charAt", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(runes)) {
+			return nil, fmt.Errorf("String index %d out of range.", index)
+		}
+		return string(runes[index]), nil
+	})
+	defineStringFunction("ord", 1, func(args []interface{}) (interface{}, error) {
+		r, size := utf8.DecodeRuneInString(args[0].(string))
+		if size == 0 {
+			return nil, fmt.Errorf("ord: empty string.")
+		}
+		return int64(r), nil
+	})
+	defineNative("chr", 1, func(args []interface{}) (interface{}, error) {
+		code, err := integerArgument("chr", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if code < 0 || code > utf8.MaxRune {
+			return nil, fmt.Errorf("chr: invalid code point %d.", code)
+		}
+		return string(rune(code)), nil
+	})
+}
@@ -0,0 +1,84 @@
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdin buffers options.Reader. It is recreated whenever the reader changes.
+var stdin struct {
+	source io.Reader
+	reader *bufio.Reader
+}
+
+func inputReader() *bufio.Reader {
+	if stdin.reader == nil || stdin.source != options.Reader {
+		stdin.source = options.Reader
+		if reader, ok := options.Reader.(*bufio.Reader); ok {
+			stdin.reader = reader
+		} else {
+			stdin.reader = bufio.NewReader(options.Reader)
+		}
+	}
+	return stdin.reader
+}
+
+// readLine returns the next input line without its line terminator, or nil
+// at the end of the input
+func readLine(native string) (interface{}, error) {
+	line, err := inputReader().ReadString('\n')
+	if err == io.EOF {
+		if line == "" {
+			return nil, nil
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %v.", native, err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func init() {
+	defineNative("input", 1, func(args []interface{}) (interface{}, error) {
+		fmt.Fprint(options.Writer, stringify(args[0]))
+		return readLine("input")
+	})
+	defineNative("readLine", 0, func(args []interface{}) (interface{}, error) {
+		return readLine("readLine")
+	})
+	defineNative("readAll", 0, func(args []interface{}) (interface{}, error) {
+		dat, err := io.ReadAll(inputReader())
+		if err != nil {
+			return nil, fmt.Errorf("readAll: %v.", err)
+		}
+		return string(dat), nil
+	})
+	defineNative("args", 0, func(args []interface{}) (interface{}, error) {
+		elements := make([]interface{}, len(options.Args))
+		for i, arg := range options.Args {
+			elements[i] = arg
+		}
+		return NewList(elements), nil
+	})
+	// env returns nil for unset variables
+	defineNative("env", 1, func(args []interface{}) (interface{}, error) {
+		name, err := stringArgument("env", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value, nil
+		}
+		return nil, nil
+	})
+	// exit unwinds the interpreter; the process exit is left to the embedder
+	defineNative("exit", 1, func(args []interface{}) (interface{}, error) {
+		code, err := integerArgument("exit", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ExitError{Code: int(code)}
+	})
+}
@@ -0,0 +1,124 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Clock is the time source of the time natives. Embedders and tests can
+// replace it through Options.Clock.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// startTime is the origin of clock(). It is reset by SetOptions.
+var startTime = time.Now()
+
+// layouts are the names accepted by formatTime and parseTime in place of
+// a Go reference layout
+var layouts = map[string]string{
+	"RFC3339":  time.RFC3339,
+	"RFC1123":  time.RFC1123,
+	"DateTime": "2006-01-02 15:04:05",
+	"Date":     "2006-01-02",
+	"Time":     "15:04:05",
+}
+
+// Times are numbers of seconds since the Unix epoch
+func toTime(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+func fromTime(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+func layoutArgument(name string, args []interface{}, i int) (string, error) {
+	layout, err := stringArgument(name, args, i)
+	if err != nil {
+		return "", err
+	}
+	if named, ok := layouts[layout]; ok {
+		return named, nil
+	}
+	return layout, nil
+}
+
+func init() {
+	// clock returns the seconds elapsed since the interpreter started
+	defineNative("clock", 0, func(args []interface{}) (interface{}, error) {
+		return options.Clock.Now().Sub(startTime).Seconds(), nil
+	})
+	defineNative("now", 0, func(args []interface{}) (interface{}, error) {
+		return fromTime(options.Clock.Now()), nil
+	})
+	// formatTime(t, layout) formats a time in UTC
+	defineNative("formatTime", 2, func(args []interface{}) (interface{}, error) {
+		t, err := numberArgument("formatTime", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		layout, err := layoutArgument("formatTime", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return toTime(t).Format(layout), nil
+	})
+	defineNative("parseTime", 2, func(args []interface{}) (interface{}, error) {
+		s, err := stringArgument("parseTime", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		layout, err := layoutArgument("parseTime", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return nil, fmt.Errorf("parseTime: %v.", err)
+		}
+		return fromTime(t), nil
+	})
+	defineNative("sleep", 1, func(args []interface{}) (interface{}, error) {
+		ms, err := numberArgument("sleep", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if ms > 0 {
+			options.Clock.Sleep(time.Duration(ms * float64(time.Millisecond)))
+		}
+		return nil, nil
+	})
+	// duration("1h30m") returns the number of seconds in the duration
+	defineNative("duration", 1, func(args []interface{}) (interface{}, error) {
+		s, err := stringArgument("duration", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("duration: %v.", err)
+		}
+		return d.Seconds(), nil
+	})
+	defineNative("formatDuration", 1, func(args []interface{}) (interface{}, error) {
+		seconds, err := numberArgument("formatDuration", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return time.Duration(seconds * float64(time.Second)).String(), nil
+	})
+}
@@ -3,21 +3,25 @@ package parseerror
 import (
 	"fmt"
 	"github.com/jfourkiotis/golox/token"
+	"io"
 	"os"
 )
 
 // HadError is true if a scanner/parser error was encountered
 var HadError = false
 
-// LogMessage reports in stderr an error encountered during parsing
+// Output is where errors are reported, stderr unless replaced by tests
+var Output io.Writer = os.Stderr
+
+// LogMessage reports in Output an error encountered during parsing
 func LogMessage(line int, message string) {
 	report(line, "", message)
 	HadError = true
 }
 
-// LogError reports in stderr an error encountered during parsing
+// LogError reports in Output an error encountered during parsing
 func LogError(err error) {
-	fmt.Fprintf(os.Stderr, "%v\n", err.Error())
+	fmt.Fprintf(Output, "%v\n", err.Error())
 	HadError = true
 }
 
@@ -30,5 +34,5 @@ func MakeError(tok token.Token, message string) error {
 }
 
 func report(line int, where string, message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Error: %s: %s\n", line, where, message)
+	fmt.Fprintf(Output, "[line %d] Error: %s: %s\n", line, where, message)
 }
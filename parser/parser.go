@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"github.com/jfourkiotis/golox/ast"
 	"github.com/jfourkiotis/golox/parseerror"
 	"github.com/jfourkiotis/golox/token"
@@ -10,12 +11,20 @@ import (
 program    -> declaration* EOF ;
 declaration -> classDecl
             | varDecl
+            | constDecl
             | funDecl
 			| stmt
-varDecl    -> "var" IDENTIFIER ( "=" expression )? ";" ;
+varDecl    -> "var" IDENTIFIER ( "=" expression )? ";"
+			| "var" ( "[" identifiers "]" | "{" identifiers "}" ) "=" expression ";" ;
+identifiers -> IDENTIFIER ( "," IDENTIFIER )* ;
+constDecl  -> "const" IDENTIFIER "=" expression ";"
+			| "const" ( "[" identifiers "]" | "{" identifiers "}" ) "=" expression ";" ;
 funDecl    -> "fun" function ;
 classDecl  -> "class" IDENTIFIER  ( "<" IDENTIFIER )? "{" (function|property)* "}" ;
 function   -> "class"? IDENTIFIER "(" parameters? ")" block ;
+parameters -> parameter ( "," parameter )* ( "," "..." IDENTIFIER )?
+			| "..." IDENTIFIER ;
+parameter  -> IDENTIFIER ( "=" assignment )? ;
 property   -> IDENTIFIER block ;
 stmt       -> exprStmt
             | ifStmt
@@ -37,36 +46,68 @@ exprStmt   -> expression ";" ;
 printStmt  -> "print" expression ";" ;
 expression -> comma ;
 comma      -> assignment ( "," assignment ) * ;
-assignment -> (call "." )? IDENTIFIER "=" assignment
+assignment -> (call "." )? IDENTIFIER ( "=" | "+=" | "-=" | "*=" | "/=" | "%=" | "**=" ) assignment
+			| "[" target ( "," target )* "]" "=" assignment
 			| logic_or ;
+target     -> (call "." )? IDENTIFIER ;
 logic_or   -> logic_and ( "or" logic_and )* ;
 logic_and  -> ternary ( "and" ternary ) * ;
-ternary    -> equality "?"  expression ":" expression ;
+ternary    -> coalesce "?"  expression ":" expression ;
+coalesce   -> equality ( "??" equality )* ;
 equality   -> comparison ( ( "!=" | "==") comparison )* ;
-comparison -> addition ( ( ">" | ">=" | "<" | "<=") addition )*;
+comparison -> bitor ( ( ">" | ">=" | "<" | "<=") bitor )*;
+bitor      -> bitxor ( "|" bitxor )* ;
+bitxor     -> bitand ( "^" bitand )* ;
+bitand     -> shift ( "&" shift )* ;
+shift      -> addition ( ( "<<" | ">>" ) addition )* ;
 addition   -> multiplication ( ( "+" | "-" ) multiplication )*;
-multiplication -> unary ( ( "/" | "*" ) unary )*;
-unary      -> ( "!" | "-" ) unary;
+multiplication -> unary ( ( "/" | "*" | "%" ) unary )*;
+unary      -> ( "!" | "-" | "~" ) unary
+			| ( "++" | "--" ) unary
 			| power ;
-power      -> call ( "**" unary ) *
+power      -> postfix ( "**" unary ) *
+postfix    -> call ( "++" | "--" )? ;
 call       -> primary ( "(" arguments? ")" | "." IDENTIFIER )* ;
-arguments  -> expression ( "," expression )* ;
+arguments  -> argument ( "," argument )* ;
+argument   -> "..." assignment | IDENTIFIER ":" assignment | assignment ;
 primary    -> NUMBER | STRING | "false" | "true" | "nil" | "this" | "super"
 			| "(" expression ")"
+			| "[" ( assignment ( "," assignment )* )? "]"
 			| IDENTIFIER ;
 */
 
+// DefaultMaxArguments is the default limit of function parameters and call
+// arguments
+const DefaultMaxArguments = 255
+
+// Options configure a Parser
+type Options struct {
+	// MaxArguments limits the number of parameters of a function and the
+	// number of arguments of a call. Zero means DefaultMaxArguments.
+	MaxArguments int
+}
+
 // Parser will transform an array of tokens to an AST.
 // Use parser.New to create a new Parser. Do not create a Parser directly
 type Parser struct {
 	tokens  []token.Token
 	current int
 	inloop  bool // used when checking stray break/continue statements
+	yielded bool // a yield statement was found in the current function
+	options Options
 }
 
-// New creates a new parser
+// New creates a new parser with the default options
 func New(tokens []token.Token) Parser {
-	return Parser{tokens, 0, false}
+	return NewWithOptions(tokens, Options{})
+}
+
+// NewWithOptions creates a new parser
+func NewWithOptions(tokens []token.Token, options Options) Parser {
+	if options.MaxArguments <= 0 {
+		options.MaxArguments = DefaultMaxArguments
+	}
+	return Parser{tokens, 0, false, false, options}
 }
 
 // Parse is the driver function that begins parsing
@@ -94,7 +135,7 @@ func (p *Parser) declaration() ast.Stmt {
 
 	if p.match(token.CLASS) {
 		stmt, err = p.classDeclaration()
-	} else if p.match(token.VAR) {
+	} else if p.match(token.VAR, token.CONST) {
 		stmt, err = p.varDeclaration()
 	} else if p.match(token.FUN) {
 		stmt, err = p.funDeclaration("function")
@@ -145,33 +186,57 @@ func (p *Parser) classDeclaration() (ast.Stmt, error) {
 	return &ast.Class{Name: name, Methods: methods, ClassMethods: classmethods, SuperClass: superclass}, nil
 }
 
-func (p *Parser) methodArguments(kind string) ([]token.Token, error) {
+// methodArguments parses the parameter list of a function. It returns the
+// parameter names, their default values (nil for required parameters) and
+// whether the last parameter is variadic.
+func (p *Parser) methodArguments(kind string, name token.Token) ([]token.Token, []ast.Expr, bool, error) {
 	_, err := p.consume(token.LEFTPAREN, "Expected '(' after "+kind+" name.")
 	if err != nil {
-		return nil, err
+		return nil, nil, false, err
 	}
 
 	parameters := make([]token.Token, 0)
+	defaults := make([]ast.Expr, 0)
+	variadic := false
 	if !p.check(token.RIGHTPAREN) {
 		for {
-			if len(parameters) >= 8 {
-				return nil, parseerror.MakeError(p.peek(), "Cannot have more than 8 parameters.")
+			if len(parameters) >= p.options.MaxArguments {
+				return nil, nil, false, parseerror.MakeError(p.peek(),
+					fmt.Sprintf("Cannot have more than %d parameters in %s '%s'.", p.options.MaxArguments, kind, name.Lexeme))
 			}
 
+			variadic = p.match(token.ELLIPSIS)
 			param, err2 := p.consume(token.IDENTIFIER, "Expected parameter name.")
 			if err2 != nil {
-				return nil, err2
+				return nil, nil, false, err2
+			}
+
+			var value ast.Expr
+			if p.match(token.EQUAL) {
+				if variadic {
+					return nil, nil, false, parseerror.MakeError(p.previous(), "A variadic parameter cannot have a default value.")
+				}
+				value, err2 = p.assignment()
+				if err2 != nil {
+					return nil, nil, false, err2
+				}
+			} else if !variadic && len(defaults) > 0 && defaults[len(defaults)-1] != nil {
+				return nil, nil, false, parseerror.MakeError(param, "A parameter without a default value cannot follow one with a default value.")
 			}
 
 			parameters = append(parameters, param)
+			defaults = append(defaults, value)
 
 			if !p.match(token.COMMA) {
 				break
 			}
+			if variadic {
+				return nil, nil, false, parseerror.MakeError(param, "The variadic parameter must be the last one.")
+			}
 		}
 	}
 	_, err = p.consume(token.RIGHTPAREN, "Expected ')' after parameters.")
-	return parameters, err
+	return parameters, defaults, variadic, err
 }
 
 func (p *Parser) funDeclaration(kind string) (*ast.Function, error) {
@@ -190,8 +255,10 @@ func (p *Parser) funDeclaration(kind string) (*ast.Function, error) {
 	}
 
 	var parameters []token.Token
+	var defaults []ast.Expr
+	variadic := false
 	if p.check(token.LEFTPAREN) {
-		parameters, err = p.methodArguments(kind)
+		parameters, defaults, variadic, err = p.methodArguments(kind, name)
 		if err != nil {
 			return nil, err
 		}
@@ -202,15 +269,24 @@ func (p *Parser) funDeclaration(kind string) (*ast.Function, error) {
 		return nil, err
 	}
 
+	oldYielded := p.yielded
+	p.yielded = false
 	body, err := p.block()
+	isGenerator := p.yielded
+	p.yielded = oldYielded
 	if err != nil {
 		return nil, err
 	}
 
-	return &ast.Function{Name: name, Params: parameters, Body: body, EnvIndex: -1, IsClassMethod: isClassMethod}, nil
+	return &ast.Function{Name: name, Params: parameters, Defaults: defaults, Variadic: variadic, Body: body, EnvIndex: -1, IsClassMethod: isClassMethod, IsGenerator: isGenerator}, nil
 }
 
+// varDeclaration parses the rest of a 'var' or a 'const' declaration
 func (p *Parser) varDeclaration() (ast.Stmt, error) {
+	constant := p.previous().Type == token.CONST
+	if p.match(token.LEFTBRACKET, token.LEFTBRACE) {
+		return p.destructuringDeclaration(constant)
+	}
 	name, err := p.consume(token.IDENTIFIER, "Expected variable name.")
 	if err != nil {
 		return nil, err
@@ -222,12 +298,52 @@ func (p *Parser) varDeclaration() (ast.Stmt, error) {
 		if err != nil {
 			return nil, err
 		}
+	} else if constant {
+		return nil, parseerror.MakeError(name, "Constants must be initialized.")
 	}
 	_, err = p.consume(token.SEMICOLON, "Expected ';' after variable declaration.")
 	if err != nil {
 		return nil, err
 	}
-	return &ast.Var{Name: name, Initializer: initializer, EnvIndex: -1}, nil
+	return &ast.Var{Name: name, Initializer: initializer, EnvIndex: -1, Const: constant}, nil
+}
+
+func (p *Parser) destructuringDeclaration(constant bool) (ast.Stmt, error) {
+	pattern := p.previous()
+	closing := token.Type(token.RIGHTBRACKET)
+	if pattern.Type == token.LEFTBRACE {
+		closing = token.RIGHTBRACE
+	}
+
+	bindings := make([]*ast.Binding, 0)
+	for {
+		name, err := p.consume(token.IDENTIFIER, "Expected variable name.")
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, &ast.Binding{Name: name, EnvIndex: -1})
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+	_, err := p.consume(closing, fmt.Sprintf("Expected '%s' after variable names.", closing))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.consume(token.EQUAL, "Expected '=' after destructuring pattern.")
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.SEMICOLON, "Expected ';' after variable declaration.")
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Destructure{Pattern: pattern, Bindings: bindings, Initializer: initializer, Const: constant}, nil
 }
 
 func (p *Parser) statement() (ast.Stmt, error) {
@@ -237,10 +353,18 @@ func (p *Parser) statement() (ast.Stmt, error) {
 		return p.whileStatement()
 	} else if p.match(token.FOR) {
 		return p.forStatement()
+	} else if p.match(token.DO) {
+		return p.doWhileStatement()
+	} else if p.check(token.IDENTIFIER) && p.checkNext(token.COLON) {
+		return p.labeledStatement()
 	} else if p.match(token.PRINT) {
 		return p.printStatement()
 	} else if p.match(token.RETURN) {
 		return p.returnStatement()
+	} else if p.match(token.YIELD) {
+		return p.yieldStatement()
+	} else if p.match(token.MATCH) {
+		return p.matchStatement()
 	} else if p.match(token.BREAK) {
 		return p.breakStatement()
 	} else if p.match(token.CONTINUE) {
@@ -260,11 +384,15 @@ func (p *Parser) breakStatement() (ast.Stmt, error) {
 		return nil, parseerror.MakeError(p.previous(), "Stray break detected.")
 	}
 	tok := p.previous()
+	var label token.Token
+	if p.match(token.IDENTIFIER) {
+		label = p.previous()
+	}
 	_, err := p.consume(token.SEMICOLON, "Expected ';' after break")
 	if err != nil {
 		return nil, err
 	}
-	return &ast.Break{Token: tok}, nil
+	return &ast.Break{Token: tok, Label: label}, nil
 }
 
 func (p *Parser) continueStatement() (ast.Stmt, error) {
@@ -272,11 +400,15 @@ func (p *Parser) continueStatement() (ast.Stmt, error) {
 		return nil, parseerror.MakeError(p.previous(), "Stray continue detected.")
 	}
 	tok := p.previous()
+	var label token.Token
+	if p.match(token.IDENTIFIER) {
+		label = p.previous()
+	}
 	_, err := p.consume(token.SEMICOLON, "Expected ';' after continue")
 	if err != nil {
 		return nil, err
 	}
-	return &ast.Continue{Token: tok}, nil
+	return &ast.Continue{Token: tok, Label: label}, nil
 }
 
 func (p *Parser) forStatement() (ast.Stmt, error) {
@@ -288,6 +420,10 @@ func (p *Parser) forStatement() (ast.Stmt, error) {
 		return nil, err
 	}
 
+	if p.check(token.IDENTIFIER) && p.checkNext(token.IN) {
+		return p.forInStatement()
+	}
+
 	// first clause (initializer)
 	var initializer ast.Stmt
 	if p.match(token.SEMICOLON) {
@@ -336,6 +472,89 @@ func (p *Parser) forStatement() (ast.Stmt, error) {
 	return &ast.For{Initializer: initializer, Condition: condition, Increment: increment, Statement: body}, nil
 }
 
+// forInStatement parses the rest of 'for (name in iterable) body'
+func (p *Parser) forInStatement() (ast.Stmt, error) {
+	name := p.advance()
+	keyword := p.advance()
+	iterable, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.RIGHTPAREN, "Expected ')' after for-in clause.")
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ForIn{Keyword: keyword, Name: name, Iterable: iterable, Statement: body}, nil
+}
+
+// labeledStatement parses 'label: <loop>'
+func (p *Parser) labeledStatement() (ast.Stmt, error) {
+	label := p.advance()
+	p.advance() // skip ':'
+
+	var loop ast.Stmt
+	var err error
+	if p.match(token.FOR) {
+		loop, err = p.forStatement()
+	} else if p.match(token.WHILE) {
+		loop, err = p.whileStatement()
+	} else if p.match(token.DO) {
+		loop, err = p.doWhileStatement()
+	} else {
+		return nil, parseerror.MakeError(label, "Labels can only be applied to loops.")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch l := loop.(type) {
+	case *ast.For:
+		l.Label = label
+	case *ast.ForIn:
+		l.Label = label
+	case *ast.While:
+		l.Label = label
+	case *ast.DoWhile:
+		l.Label = label
+	}
+	return loop, nil
+}
+
+func (p *Parser) doWhileStatement() (ast.Stmt, error) {
+	oldInLoop := p.inloop
+	defer p.resetLoop(oldInLoop)
+	p.inloop = true
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.WHILE, "Expected 'while' after do-while body.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.LEFTPAREN, "Expected '(' after 'while'.")
+	if err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.RIGHTPAREN, "Expected ')' after condition.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.SEMICOLON, "Expected ';' after do-while condition.")
+	if err != nil {
+		return nil, err
+	}
+	return &ast.DoWhile{Statement: body, Condition: condition}, nil
+}
+
 func (p *Parser) whileStatement() (ast.Stmt, error) {
 	oldInLoop := p.inloop
 	defer p.resetLoop(oldInLoop)
@@ -421,6 +640,184 @@ func (p *Parser) returnStatement() (ast.Stmt, error) {
 	return &ast.Return{Keyword: keyword, Value: value}, nil
 }
 
+func (p *Parser) yieldStatement() (ast.Stmt, error) {
+	keyword := p.previous()
+	p.yielded = true
+
+	var value ast.Expr
+	var err error
+	if !p.check(token.SEMICOLON) {
+		value, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.consume(token.SEMICOLON, "Expected ';' after yield value.")
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Yield{Keyword: keyword, Value: value}, nil
+}
+
+func (p *Parser) matchStatement() (ast.Stmt, error) {
+	keyword := p.previous()
+	_, err := p.consume(token.LEFTPAREN, "Expected '(' after 'match'.")
+	if err != nil {
+		return nil, err
+	}
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.RIGHTPAREN, "Expected ')' after match value.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(token.LEFTBRACE, "Expected '{' before match cases.")
+	if err != nil {
+		return nil, err
+	}
+
+	cases := make([]*ast.MatchCase, 0)
+	for !p.check(token.RIGHTBRACE) && !p.isAtEnd() {
+		matchCase, err := p.matchCase()
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, matchCase)
+	}
+	_, err = p.consume(token.RIGHTBRACE, "Expected '}' after match cases.")
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Match{Keyword: keyword, Value: value, Cases: cases}, nil
+}
+
+// matchCase parses 'case <pattern>, ... [if <guard>] => <statement>'
+func (p *Parser) matchCase() (*ast.MatchCase, error) {
+	keyword, err := p.consume(token.CASE, "Expected 'case'.")
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]ast.Pattern, 0)
+	for {
+		pattern, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+	var guard ast.Expr
+	if p.match(token.IF) {
+		guard, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(token.ARROW, "Expected '=>' after case pattern.")
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.MatchCase{Keyword: keyword, Patterns: patterns, Guard: guard, Body: body}, nil
+}
+
+func (p *Parser) pattern() (ast.Pattern, error) {
+	if p.match(token.FALSE) {
+		return &ast.LiteralPattern{Value: &ast.Literal{Value: false}}, nil
+	} else if p.match(token.TRUE) {
+		return &ast.LiteralPattern{Value: &ast.Literal{Value: true}}, nil
+	} else if p.match(token.NIL) {
+		return &ast.LiteralPattern{Value: &ast.Literal{Value: nil}}, nil
+	} else if p.match(token.NUMBER, token.STRING) {
+		return &ast.LiteralPattern{Value: &ast.Literal{Value: p.previous().Literal}}, nil
+	} else if p.match(token.MINUS) {
+		operator := p.previous()
+		number, err := p.consume(token.NUMBER, "Expected number after '-'.")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.LiteralPattern{Value: &ast.Unary{Operator: operator, Right: &ast.Literal{Value: number.Literal}}}, nil
+	} else if p.match(token.LEFTBRACKET) {
+		return p.listPattern()
+	} else if p.match(token.IDENTIFIER) {
+		name := p.previous()
+		if p.match(token.LEFTPAREN) {
+			return p.classPattern(name)
+		} else if name.Lexeme == "_" {
+			return &ast.WildcardPattern{Token: name}, nil
+		}
+		return &ast.BindingPattern{Name: name, EnvIndex: -1}, nil
+	}
+	return nil, parseerror.MakeError(p.peek(), "Expected pattern.")
+}
+
+// listPattern parses the rest of '[<pattern>, ... [, ...<pattern>]]'
+func (p *Parser) listPattern() (ast.Pattern, error) {
+	bracket := p.previous()
+	elements := make([]ast.Pattern, 0)
+	var rest ast.Pattern
+	if !p.check(token.RIGHTBRACKET) {
+		for {
+			if p.match(token.ELLIPSIS) {
+				var err error
+				if rest, err = p.pattern(); err != nil {
+					return nil, err
+				}
+				break
+			}
+			element, err := p.pattern()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	_, err := p.consume(token.RIGHTBRACKET, "Expected ']' after list pattern.")
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ListPattern{Bracket: bracket, Elements: elements, Rest: rest}, nil
+}
+
+// classPattern parses the rest of '<class>(<field>[: <pattern>], ...)'
+func (p *Parser) classPattern(class token.Token) (ast.Pattern, error) {
+	fields := make([]*ast.FieldPattern, 0)
+	if !p.check(token.RIGHTPAREN) {
+		for {
+			name, err := p.consume(token.IDENTIFIER, "Expected field name.")
+			if err != nil {
+				return nil, err
+			}
+			var value ast.Pattern = &ast.BindingPattern{Name: name, EnvIndex: -1}
+			if p.match(token.COLON) {
+				if value, err = p.pattern(); err != nil {
+					return nil, err
+				}
+			}
+			fields = append(fields, &ast.FieldPattern{Name: name, Value: value})
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	_, err := p.consume(token.RIGHTPAREN, "Expected ')' after field patterns.")
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ClassPattern{Class: &ast.Variable{Name: class, EnvIndex: -1, EnvDepth: -1}, Fields: fields}, nil
+}
+
 func (p *Parser) printStatement() (ast.Stmt, error) {
 	expr, err := p.expression()
 	if err != nil {
@@ -484,12 +881,46 @@ func (p *Parser) assignment() (ast.Expr, error) {
 			return &ast.Assign{Name: variable.Name, Value: value, EnvIndex: -1, EnvDepth: -1}, nil
 		} else if get, ok := expr.(*ast.Get); ok {
 			return &ast.Set{Object: get.Expression, Name: get.Name, Value: value}, nil
+		} else if list, ok := expr.(*ast.ListLiteral); ok {
+			return p.destructuringAssignment(list, equals, value)
 		}
 		return nil, parseerror.MakeError(equals, "Invalid assignment target.")
+	} else if p.match(token.PLUSEQUAL, token.MINUSEQUAL, token.STAREQUAL, token.SLASHEQUAL, token.PERCENTEQUAL, token.POWEREQUAL) {
+		operator := p.previous()
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		return p.compoundAssignment(expr, operator, value, false)
 	}
 	return expr, nil
 }
 
+// destructuringAssignment checks that every element of the list is a
+// variable or a property
+func (p *Parser) destructuringAssignment(list *ast.ListLiteral, equals token.Token, value ast.Expr) (ast.Expr, error) {
+	for _, element := range list.Elements {
+		switch element.(type) {
+		case *ast.Variable, *ast.Get:
+		default:
+			return nil, parseerror.MakeError(equals, "Invalid assignment target.")
+		}
+	}
+	if len(list.Elements) == 0 {
+		return nil, parseerror.MakeError(equals, "Invalid assignment target.")
+	}
+	return &ast.DestructureAssign{Bracket: list.Bracket, Targets: list.Elements, Value: value}, nil
+}
+
+// compoundAssignment checks that target is a variable or a property
+func (p *Parser) compoundAssignment(target ast.Expr, operator token.Token, value ast.Expr, postfix bool) (ast.Expr, error) {
+	switch target.(type) {
+	case *ast.Variable, *ast.Get:
+		return &ast.CompoundAssign{Target: target, Operator: operator, Value: value, Postfix: postfix}, nil
+	}
+	return nil, parseerror.MakeError(operator, "Invalid assignment target.")
+}
+
 func (p *Parser) or() (ast.Expr, error) {
 	expr, err := p.and()
 	if err != nil {
@@ -523,8 +954,26 @@ func (p *Parser) and() (ast.Expr, error) {
 	return expr, nil
 }
 
+// coalesce parses 'a ?? b', which evaluates b only if a is nil. It binds
+// tighter than '?:', so 'a ?? b ? c : d' tests 'a ?? b'.
+func (p *Parser) coalesce() (ast.Expr, error) {
+	expr, err := p.equality()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(token.QMARKQMARK) {
+		operator := p.previous()
+		right, err := p.equality()
+		if err != nil {
+			return nil, err
+		}
+		expr = &ast.Logical{Left: expr, Operator: operator, Right: right}
+	}
+	return expr, nil
+}
+
 func (p *Parser) ternary() (ast.Expr, error) {
-	cond, err := p.equality()
+	cond, err := p.coalesce()
 	if err != nil {
 		return nil, err
 	}
@@ -566,12 +1015,84 @@ func (p *Parser) equality() (ast.Expr, error) {
 }
 
 func (p *Parser) comparison() (ast.Expr, error) {
-	expr, err := p.addition()
+	expr, err := p.bitor()
 	if err != nil {
 		return nil, err
 	}
 
 	for p.match(token.GREATER, token.GREATEREQUAL, token.LESS, token.LESSEQUAL) {
+		operator := p.previous()
+		right, err := p.bitor()
+		if err != nil {
+			return nil, err
+		}
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) bitor() (ast.Expr, error) {
+	expr, err := p.bitxor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(token.PIPE) {
+		operator := p.previous()
+		right, err := p.bitxor()
+		if err != nil {
+			return nil, err
+		}
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) bitxor() (ast.Expr, error) {
+	expr, err := p.bitand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(token.CARET) {
+		operator := p.previous()
+		right, err := p.bitand()
+		if err != nil {
+			return nil, err
+		}
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) bitand() (ast.Expr, error) {
+	expr, err := p.shift()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(token.AMPERSAND) {
+		operator := p.previous()
+		right, err := p.shift()
+		if err != nil {
+			return nil, err
+		}
+		expr = &ast.Binary{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) shift() (ast.Expr, error) {
+	expr, err := p.addition()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(token.LESSLESS, token.GREATERGREATER) {
 		operator := p.previous()
 		right, err := p.addition()
 		if err != nil {
@@ -607,7 +1128,7 @@ func (p *Parser) multiplication() (ast.Expr, error) {
 		return nil, err
 	}
 
-	for p.match(token.STAR, token.SLASH) {
+	for p.match(token.STAR, token.SLASH, token.PERCENT) {
 		operator := p.previous()
 		right, err := p.unary()
 		if err != nil {
@@ -620,20 +1141,27 @@ func (p *Parser) multiplication() (ast.Expr, error) {
 }
 
 func (p *Parser) unary() (ast.Expr, error) {
-	if p.match(token.BANG, token.MINUS) {
+	if p.match(token.BANG, token.MINUS, token.TILDE) {
 		operator := p.previous()
 		right, err := p.unary()
 		if err != nil {
 			return nil, err
 		}
 		return &ast.Unary{Operator: operator, Right: right}, nil
+	} else if p.match(token.PLUSPLUS, token.MINUSMINUS) {
+		operator := p.previous()
+		target, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return p.compoundAssignment(target, operator, &ast.Literal{Value: int64(1)}, false)
 	}
 
 	return p.power()
 }
 
 func (p *Parser) power() (ast.Expr, error) {
-	expr, err := p.call()
+	expr, err := p.postfix()
 	if err != nil {
 		return nil, err
 	}
@@ -649,6 +1177,18 @@ func (p *Parser) power() (ast.Expr, error) {
 	return expr, nil
 }
 
+func (p *Parser) postfix() (ast.Expr, error) {
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(token.PLUSPLUS, token.MINUSMINUS) {
+		return p.compoundAssignment(expr, p.previous(), &ast.Literal{Value: int64(1)}, true)
+	}
+	return expr, nil
+}
+
 func (p *Parser) call() (ast.Expr, error) {
 	expr, err := p.primary()
 
@@ -656,6 +1196,7 @@ func (p *Parser) call() (ast.Expr, error) {
 		return nil, err
 	}
 
+	optional := false
 	for {
 		if p.match(token.LEFTPAREN) {
 			expr, err = p.finishCall(expr)
@@ -668,23 +1209,40 @@ func (p *Parser) call() (ast.Expr, error) {
 				return nil, err
 			}
 			expr = &ast.Get{Expression: expr, Name: name}
+		} else if p.match(token.QMARKDOT) {
+			name, err := p.consume(token.IDENTIFIER, "Expected property name after '?.'")
+			if err != nil {
+				return nil, err
+			}
+			expr = &ast.Get{Expression: expr, Name: name, Optional: true}
+			optional = true
 		} else {
 			break
 		}
 	}
+	if optional {
+		return &ast.OptionalChain{Expression: expr}, nil
+	}
 	return expr, nil
 }
 
 func (p *Parser) finishCall(callee ast.Expr) (ast.Expr, error) {
 	args := make([]ast.Expr, 0)
+	named := false
 	if !p.check(token.RIGHTPAREN) {
 		for {
-			arg, err := p.assignment() // we don't want the comma operator here
+			arg, err := p.argument()
 			if err != nil {
 				return nil, err
 			}
-			if len(args) >= 8 {
-				return nil, parseerror.MakeError(p.peek(), "Cannot have more than 8 arguments.")
+			if len(args) >= p.options.MaxArguments {
+				return nil, parseerror.MakeError(p.peek(),
+					fmt.Sprintf("Cannot have more than %d arguments in call to '%s'.", p.options.MaxArguments, calleeName(callee)))
+			}
+			if _, ok := arg.(*ast.NamedArgument); ok {
+				named = true
+			} else if named {
+				return nil, parseerror.MakeError(p.previous(), "Positional arguments cannot follow named arguments.")
 			}
 			args = append(args, arg)
 			if !p.match(token.COMMA) {
@@ -700,6 +1258,41 @@ func (p *Parser) finishCall(callee ast.Expr) (ast.Expr, error) {
 	return &ast.Call{Callee: callee, Paren: paren, Arguments: args}, nil
 }
 
+// calleeName names the function of a call in error messages
+func calleeName(callee ast.Expr) string {
+	switch c := callee.(type) {
+	case *ast.Variable:
+		return c.Name.Lexeme
+	case *ast.Get:
+		return c.Name.Lexeme
+	case *ast.Super:
+		return c.Method.Lexeme
+	}
+	return callee.String()
+}
+
+// argument parses a positional, a spread or a named call argument
+func (p *Parser) argument() (ast.Expr, error) {
+	if p.match(token.ELLIPSIS) {
+		ellipsis := p.previous()
+		expr, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Spread{Ellipsis: ellipsis, Expression: expr}, nil
+	}
+	if p.check(token.IDENTIFIER) && p.checkNext(token.COLON) {
+		name := p.advance()
+		p.advance() // consume ':'
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.NamedArgument{Name: name, Value: value}, nil
+	}
+	return p.assignment() // we don't want the comma operator here
+}
+
 func (p *Parser) primary() (ast.Expr, error) {
 	if p.match(token.FALSE) {
 		return &ast.Literal{Value: false}, nil
@@ -732,6 +1325,26 @@ func (p *Parser) primary() (ast.Expr, error) {
 			return nil, err
 		}
 		return &ast.Grouping{Expression: expr}, nil
+	} else if p.match(token.LEFTBRACKET) {
+		bracket := p.previous()
+		elements := make([]ast.Expr, 0)
+		if !p.check(token.RIGHTBRACKET) {
+			for {
+				element, err := p.assignment()
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+				if !p.match(token.COMMA) {
+					break
+				}
+			}
+		}
+		_, err := p.consume(token.RIGHTBRACKET, "Expected ']' after list elements.")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ListLiteral{Bracket: bracket, Elements: elements}, nil
 	} else if p.match(token.IDENTIFIER) {
 		return &ast.Variable{Name: p.previous(), EnvIndex: -1, EnvDepth: -1}, nil
 	}
@@ -770,6 +1383,14 @@ func (p *Parser) check(tp token.Type) bool {
 	return p.peek().Type == tp
 }
 
+// checkNext checks the type of the token after the current one
+func (p *Parser) checkNext(tp token.Type) bool {
+	if p.isAtEnd() || p.current+1 >= len(p.tokens) {
+		return false
+	}
+	return p.tokens[p.current+1].Type == tp
+}
+
 func (p *Parser) isAtEnd() bool {
 	return p.peek().Type == token.EOF
 }
@@ -791,7 +1412,7 @@ func (p *Parser) synchronize() {
 			return
 		}
 		switch p.peek().Type {
-		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN:
+		case token.CLASS, token.FUN, token.VAR, token.CONST, token.FOR, token.DO, token.IF, token.WHILE, token.PRINT, token.RETURN, token.YIELD, token.MATCH:
 			return
 		}
 		p.advance()
@@ -1,9 +1,11 @@
 package parser
 
 import (
+	"fmt"
 	"github.com/jfourkiotis/golox/ast"
 	"github.com/jfourkiotis/golox/scanner"
 	"github.com/jfourkiotis/golox/token"
+	"strings"
 	"testing"
 )
 
@@ -19,9 +21,14 @@ func testIntegerLiteral(expression ast.Expr, expected float64, t *testing.T) {
 		t.Fatalf("result is not ast.Literal. Got=%T", expression)
 	}
 
-	val, ok := literal.Value.(float64)
-	if !ok {
-		t.Fatalf("Literal.Value type not float64, got=%T", val)
+	var val float64
+	switch v := literal.Value.(type) {
+	case int64:
+		val = float64(v)
+	case float64:
+		val = v
+	default:
+		t.Fatalf("Literal.Value type not int64 or float64, got=%T", literal.Value)
 	}
 
 	if val != expected {
@@ -48,6 +55,60 @@ func TestParseNumbers(t *testing.T) {
 	}
 }
 
+func TestParseNumberTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"5", int64(5)},
+		{"2.4", 2.4},
+		{"5.0", 5.0},
+		{"9223372036854775807", int64(9223372036854775807)},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		expression, _ := parser.expression()
+
+		literal, ok := expression.(*ast.Literal)
+		if !ok {
+			t.Fatalf("result is not ast.Literal. Got=%T", expression)
+		}
+		if literal.Value != test.expected {
+			t.Errorf("literal value not %v (%T). got=%v (%T)", test.expected, test.expected, literal.Value, literal.Value)
+		}
+	}
+}
+
+func TestParseBigNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5n", "*big.Int 5"},
+		{"123456789012345678901234567890n", "*big.Int 123456789012345678901234567890"},
+		{"1.25d", "*big.Rat 5/4"},
+		{"3d", "*big.Rat 3/1"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		expression, _ := parser.expression()
+
+		literal, ok := expression.(*ast.Literal)
+		if !ok {
+			t.Fatalf("result is not ast.Literal. Got=%T", expression)
+		}
+		if got := fmt.Sprintf("%T %v", literal.Value, literal.Value); got != test.expected {
+			t.Errorf("literal value not %s. got=%s", test.expected, got)
+		}
+	}
+}
+
 func TestParseStrings(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -179,6 +240,12 @@ func TestParseBinaryOperators(t *testing.T) {
 		{"1 >  5", 1, ">", 5},
 		{"1 <  5", 1, "<", 5},
 		{"1 , 2", 1, ",", 2},
+		{"1 % 2", 1, "%", 2},
+		{"1 & 2", 1, "&", 2},
+		{"1 | 2", 1, "|", 2},
+		{"1 ^ 2", 1, "^", 2},
+		{"1 << 2", 1, "<<", 2},
+		{"1 >> 2", 1, ">>", 2},
 	}
 
 	for i, test := range tests {
@@ -775,6 +842,361 @@ func TestParseIfStatement(t *testing.T) {
 	}
 }
 
+func TestParseBitwisePrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 | 2 ^ 3 & 4", "(| 1 (^ 2 (& 3 4)))"},
+		{"1 & 2 << 3 + 4", "(& 1 (<< 2 (+ 3 4)))"},
+		{"1 | 2 == 3", "(== (| 1 2) 3)"},
+		{"1 << 2 < 3 >> 4", "(< (<< 1 2) (>> 3 4))"},
+		{"~1 & -2", "(& (~ 1) (- 2))"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		expression, _ := parser.expression()
+
+		if expression.String() != test.expected {
+			t.Errorf("Expected %s. Got=%s", test.expected, expression.String())
+		}
+	}
+}
+
+func TestParseCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x += 1", "(+= x 1)"},
+		{"x **= y = 2", "(**= x (= y 2))"},
+		{"a.b -= 2 * 3", "(-= (. a b) (* 2 3))"},
+		{"x++", "(x ++)"},
+		{"--a.b", "(-- (. a b))"},
+		{"-x++", "(- (x ++))"},
+		{"x++ ** 2", "(** (x ++) 2)"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		expression, err := parser.expression()
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if expression.String() != test.expected {
+			t.Errorf("Expected %s. Got=%s", test.expected, expression.String())
+		}
+	}
+}
+
+func TestParseInvalidCompoundAssignment(t *testing.T) {
+	tests := []string{"1 += 2", "a + b -= 1", "3++", "--f()"}
+
+	for _, input := range tests {
+		scanner := scanner.New(input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		if _, err := parser.expression(); err == nil {
+			t.Errorf("Expected an invalid assignment target error for %q", input)
+		}
+	}
+}
+
+func TestParseDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var [a, b] = pair;", "(var [a b] pair)"},
+		{"var {x, y} = point;", "(var {x y} point)"},
+		{"[a, b] = [b, a];", "((= [a b] [b a]))"},
+		{"[a.x, b] = [];", "((= [(. a x) b] []))"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		testExpectStatementsLen(statements, 1, t)
+
+		if statements[0].String() != test.expected {
+			t.Errorf("Expected %s. Got=%s", test.expected, statements[0].String())
+		}
+	}
+}
+
+func TestParseInvalidDestructuring(t *testing.T) {
+	tests := []string{"var [a, 1] = x;", "var {a, b] = x;", "var [a];", "[a, 1] = x;", "[] = x;"}
+
+	for _, input := range tests {
+		scanner := scanner.New(input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		for _, stmt := range statements {
+			if stmt != nil {
+				t.Errorf("Expected a parse error for %q. Got=%s", input, stmt.String())
+			}
+		}
+	}
+}
+
+func TestParseConstDeclaration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const A = 1;", "(const A 1)"},
+		{"const [a, b] = pair;", "(const [a b] pair)"},
+		{"var a = 1;", "(var a 1)"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		testExpectStatementsLen(statements, 1, t)
+
+		if statements[0].String() != test.expected {
+			t.Errorf("Expected %s. Got=%s", test.expected, statements[0].String())
+		}
+	}
+
+	scanner := scanner.New("const A;")
+	parser := New(scanner.ScanTokens())
+	statements := parser.Parse()
+	if len(statements) != 1 || statements[0] != nil {
+		t.Errorf("Expected an error for an uninitialized constant")
+	}
+}
+
+func TestParseForInStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x in xs) print x;", "(for x in xs (print x))"},
+		{"for (i in range(0, 10, 2)) {}", "(for i in (call range 0 10 2 ) ())"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		testExpectStatementsLen(statements, 1, t)
+
+		if statements[0] == nil || statements[0].String() != test.expected {
+			t.Errorf("Expected %s. Got=%v", test.expected, statements[0])
+		}
+	}
+}
+
+func TestParseGenerator(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		isGenerator bool
+	}{
+		{"fun f() { yield 1; yield; }", "(fun f () ((yield 1) (yield) ))", true},
+		{"fun f() { fun g() { yield 1; } return g; }", "(fun f () ((fun g () ((yield 1) )) (return g ) ))", false},
+		{"fun f() { return 1; }", "(fun f () ((return 1 ) ))", false},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		testExpectStatementsLen(statements, 1, t)
+
+		function, ok := statements[0].(*ast.Function)
+		if !ok {
+			t.Fatalf("Expected *ast.Function. Got=%T", statements[0])
+		}
+		if function.String() != test.expected {
+			t.Errorf("Expected %s. Got=%s", test.expected, function.String())
+		}
+		if function.IsGenerator != test.isGenerator {
+			t.Errorf("Expected IsGenerator=%v for %q", test.isGenerator, test.input)
+		}
+	}
+}
+
+func TestParseMatchStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (v) { case 1, -2 => print 1; case _ => print 2; }`, "(match v (case 1 (- 2) (print 1)) (case _ (print 2)))"},
+		{`match (v) { case "x", nil, true => {} }`, "(match v (case x <nil> true ()))"},
+		{`match (p) { case Point(x: 0, y) if y > 1 => print y; }`, "(match p (case Point(x:0 y) if (> y 1) (print y)))"},
+		{`match (l) { case [a, [b], ...rest] => print a; case [..._] => print 0; }`, "(match l (case [a [b] ...rest] (print a)) (case [..._] (print 0)))"},
+		{`match (v) {}`, "(match v)"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		testExpectStatementsLen(statements, 1, t)
+
+		if statements[0] == nil || statements[0].String() != test.expected {
+			t.Errorf("Expected %s. Got=%v", test.expected, statements[0])
+		}
+	}
+
+	invalid := []string{
+		`match (v) { 1 => print 1; }`,
+		`match (v) { case 1 print 1; }`,
+		`match (v) { case [...a, b] => print 1; }`,
+		`match (v) { case a + 1 => print 1; }`,
+	}
+
+	for _, input := range invalid {
+		scanner := scanner.New(input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		parser.advance() // skip 'match'
+		if _, err := parser.matchStatement(); err == nil {
+			t.Errorf("Expected a parse error for %q", input)
+		}
+	}
+}
+
+func TestParseLoopsAndLabels(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"do print 1; while (x);", "(do (print 1) x)"},
+		{"outer: while (x) break outer;", "outer: (while x (break outer))"},
+		{"l: for (x in xs) continue l;", "l: (for x in xs (continue l))"},
+		{"l: do { continue; } while (x);", "l: (do ((continue)) x)"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		testExpectStatementsLen(statements, 1, t)
+
+		if statements[0] == nil || statements[0].String() != test.expected {
+			t.Errorf("Expected %s. Got=%v", test.expected, statements[0])
+		}
+	}
+
+	invalid := []string{"l: print 1;", "do print 1; (x);", "do print 1; while (x)", "break l;"}
+
+	for _, input := range invalid {
+		scanner := scanner.New(input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		if _, err := parser.statement(); err == nil {
+			t.Errorf("Expected a parse error for %q", input)
+		}
+	}
+}
+
+func TestParseOptionalChainingAndCoalescing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a?.b;", "((?. a b))"},
+		{"a?.b.c;", "((. (?. a b) c))"},
+		{"a?.m(1);", "((call (?. a m) 1 ))"},
+		{"a ?? b ?? c;", "((?? (?? a b) c))"},
+		{"a ?? b or c;", "((or (?? a b) c))"},
+		{"a?.b ?? 1 == 2;", "((?? (?. a b) (== 1 2)))"},
+		{"a ?? b ? c : d;", "(((?? a b) ? c : d))"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		testExpectStatementsLen(statements, 1, t)
+
+		if statements[0] == nil || statements[0].String() != test.expected {
+			t.Errorf("Expected %s. Got=%v", test.expected, statements[0])
+		}
+	}
+
+	for _, input := range []string{"a?.b = 1", "a?.b += 1", "a?.1"} {
+		scanner := scanner.New(input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		if _, err := parser.expression(); err == nil {
+			t.Errorf("Expected a parse error for %q", input)
+		}
+	}
+}
+
+func TestParseParameterAndArgumentKinds(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fun f(a, b = 2, ...rest) {}", "(fun f (a b=2 ...rest ) ())"},
+		{"fun f(...rest) {}", "(fun f (...rest ) ())"},
+		{"f(1, ...xs, b: 2);", "((call f 1 ...xs b:2 ))"},
+		{"f(c ? a : b);", "((call f (c ? a : b) ))"},
+	}
+
+	for _, test := range tests {
+		scanner := scanner.New(test.input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		statements := parser.Parse()
+		testExpectStatementsLen(statements, 1, t)
+
+		if statements[0] == nil || statements[0].String() != test.expected {
+			t.Errorf("Expected %s. Got=%v", test.expected, statements[0])
+		}
+	}
+}
+
+func TestParseInvalidParameters(t *testing.T) {
+	declarations := []string{
+		"fun f(a = 1, b) {}",
+		"fun f(...a, b) {}",
+		"fun f(...a = 1) {}",
+	}
+
+	for _, input := range declarations {
+		scanner := scanner.New(input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		parser.advance() // skip 'fun'
+		if _, err := parser.funDeclaration("function"); err == nil {
+			t.Errorf("Expected a parse error for %q", input)
+		}
+	}
+
+	calls := []string{"f(a: 1, 2)", "f(a: 1, ...b)"}
+
+	for _, input := range calls {
+		scanner := scanner.New(input)
+		tokens := scanner.ScanTokens()
+		parser := New(tokens)
+		if _, err := parser.expression(); err == nil {
+			t.Errorf("Expected a parse error for %q", input)
+		}
+	}
+}
+
 func TestParseUnaryPowerExpressions(t *testing.T) {
 	input1 := "-5**2"
 
@@ -858,3 +1280,50 @@ func TestParseUnaryPowerExpressions(t *testing.T) {
 	testIntegerLiteral(b4.Left, 2, t)
 	testIntegerLiteral(b4.Right, 5, t)
 }
+
+func TestParseArgumentLimit(t *testing.T) {
+	names := func(n int) string {
+		params := make([]string, n)
+		for i := range params {
+			params[i] = fmt.Sprintf("a%d", i)
+		}
+		return strings.Join(params, ", ")
+	}
+
+	tests := []struct {
+		max         int
+		count       int
+		expectError string
+	}{
+		{0, 8, ""},
+		{0, 255, ""},
+		{0, 256, "Cannot have more than 255"},
+		{3, 3, ""},
+		{3, 4, "Cannot have more than 3"},
+	}
+
+	for _, test := range tests {
+		options := Options{MaxArguments: test.max}
+
+		s := scanner.New(fmt.Sprintf("fun f(%s) {}", names(test.count)))
+		parser := NewWithOptions(s.ScanTokens(), options)
+		parser.advance() // skip 'fun'
+		_, err := parser.funDeclaration("function")
+		checkLimitError(err, test.expectError+" parameters in function 'f'.", test.expectError == "", t)
+
+		s = scanner.New(fmt.Sprintf("o.g(%s)", names(test.count)))
+		parser = NewWithOptions(s.ScanTokens(), options)
+		_, err = parser.expression()
+		checkLimitError(err, test.expectError+" arguments in call to 'g'.", test.expectError == "", t)
+	}
+}
+
+func checkLimitError(err error, expected string, valid bool, t *testing.T) {
+	if valid {
+		if err != nil {
+			t.Errorf("Unexpected parse error: %v", err)
+		}
+	} else if err == nil || !strings.HasSuffix(err.Error(), expected) {
+		t.Errorf("Expected error %q. Got=%v", expected, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/jfourkiotis/golox/token"
 	"os"
+	"strings"
 )
 
 // Print reports a runtime error
@@ -14,8 +15,52 @@ func Print(message string) {
 
 // Make creates a new runtime error
 func Make(token token.Token, message string) error {
-	return fmt.Errorf("%s\n[line %d]", message, token.Line)
+	return &Error{Message: message, Line: token.Line, current: token.Line}
 }
 
 // HadError is true if an evaluation error was encountered
 var HadError = false
+
+// Frame is a single entry of a Lox stack trace
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// String renders the frame as "at <function> (<file>:<line>)"
+func (f Frame) String() string {
+	return fmt.Sprintf("at %s (%s:%d)", f.Function, f.File, f.Line)
+}
+
+// Error is a runtime error together with the Lox call stack that was
+// active when it was raised. Trace starts with the innermost frame.
+type Error struct {
+	Message string
+	Line    int
+	Trace   []Frame
+	current int // the line of the next frame to be recorded
+}
+
+// Error renders the message, the line and the stack trace
+func (e *Error) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s\n[line %d]", e.Message, e.Line))
+	for _, frame := range e.Trace {
+		sb.WriteString("\n    ")
+		sb.WriteString(frame.String())
+	}
+	return sb.String()
+}
+
+// AddFrame records that the error propagated out of the given function
+func (e *Error) AddFrame(function string, file string) {
+	e.Trace = append(e.Trace, Frame{Function: function, File: file, Line: e.current})
+}
+
+// Unwind records that the error propagated out of the given function, which
+// was called from callLine of its caller
+func (e *Error) Unwind(function string, file string, callLine int) {
+	e.AddFrame(function, file)
+	e.current = callLine
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"github.com/jfourkiotis/golox/parseerror"
 	"github.com/jfourkiotis/golox/token"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 var keywords = map[string]token.Type{
@@ -26,6 +28,12 @@ var keywords = map[string]token.Type{
 	"while":    token.WHILE,
 	"break":    token.BREAK,
 	"continue": token.CONTINUE,
+	"const":    token.CONST,
+	"in":       token.IN,
+	"yield":    token.YIELD,
+	"match":    token.MATCH,
+	"case":     token.CASE,
+	"do":       token.DO,
 }
 
 // Scanner transforms the source into tokens
@@ -69,10 +77,26 @@ func (sc *Scanner) addTokenWithLiteral(tp token.Type, literal interface{}) {
 	sc.tokens = append(sc.tokens, token.Token{Type: tp, Lexeme: text, Literal: literal, Line: sc.line})
 }
 
-func (sc *Scanner) scanString() {
+// scanString scans the rest of a string literal whose opening quote has
+// been consumed. Raw strings (r"...") keep their backslashes; the others
+// process escape sequences. Triple-quoted strings may span several lines.
+func (sc *Scanner) scanString(raw bool) {
+	if sc.peek() == '"' && sc.peekNext() == '"' {
+		sc.advance()
+		sc.advance()
+		sc.scanTripleQuotedString(raw)
+		return
+	}
+
+	contentStart := sc.current
 	for sc.peek() != '"' && !sc.isAtEnd() {
 		if sc.peek() == '\n' {
 			sc.line++
+		} else if sc.peek() == '\\' && !raw && sc.peekNext() != 0 {
+			sc.advance() // an escaped character cannot close the string
+			if sc.peek() == '\n' {
+				sc.line++
+			}
 		}
 		sc.advance()
 	}
@@ -83,35 +107,283 @@ func (sc *Scanner) scanString() {
 		return
 	}
 
+	value := sc.source[contentStart:sc.current]
+
 	// the closing ".
 	sc.advance()
 
-	// trim the surrounding quotes
-	value := sc.source[sc.start+1 : sc.current-1]
+	if !raw {
+		value = unescape(value)
+	}
 	sc.addTokenWithLiteral(token.STRING, value)
 }
 
-func (sc *Scanner) scanNumber() {
-	for sc.isDigit(sc.peek()) {
+// scanTripleQuotedString scans the rest of a """...""" string. The line
+// breaks after the opening and before the closing quotes are dropped, and so
+// is the indentation common to all the lines.
+func (sc *Scanner) scanTripleQuotedString(raw bool) {
+	startLine := sc.line
+	contentStart := sc.current
+	for !sc.isAtEnd() && !(sc.peek() == '"' && sc.peekNext() == '"' && sc.peekAt(2) == '"') {
+		if sc.peek() == '\n' {
+			sc.line++
+		} else if sc.peek() == '\\' && !raw && sc.peekNext() != 0 {
+			sc.advance()
+			if sc.peek() == '\n' {
+				sc.line++
+			}
+		}
+		sc.advance()
+	}
+
+	if sc.isAtEnd() {
+		parseerror.LogMessage(startLine, "Unterminated string.")
+		return
+	}
+
+	value := trimIndent(sc.source[contentStart:sc.current])
+
+	// the closing """
+	sc.advance()
+	sc.advance()
+	sc.advance()
+
+	if !raw {
+		value = unescape(value)
+	}
+	sc.addTokenWithLiteral(token.STRING, value)
+}
+
+var escapes = map[byte]string{
+	'n':  "\n",
+	't':  "\t",
+	'r':  "\r",
+	'0':  "\x00",
+	'"':  "\"",
+	'\\': "\\",
+}
+
+// unescape replaces the escape sequences of a string literal. Unknown
+// sequences, such as the \d of a regular expression, are kept as they are.
+func unescape(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if replacement, ok := escapes[s[i+1]]; ok {
+				sb.WriteString(replacement)
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// trimIndent removes the first and the last line of s if they are blank,
+// and the indentation common to the remaining non-blank lines
+func trimIndent(s string) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > 1 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scanBlockComment skips a /* ... */ comment, which may contain nested block
+// comments
+func (sc *Scanner) scanBlockComment() {
+	startLine := sc.line
+	depth := 1
+	for depth > 0 && !sc.isAtEnd() {
+		if sc.peek() == '/' && sc.peekNext() == '*' {
+			sc.advance()
+			depth++
+		} else if sc.peek() == '*' && sc.peekNext() == '/' {
+			sc.advance()
+			depth--
+		} else if sc.peek() == '\n' {
+			sc.line++
+		}
 		sc.advance()
 	}
 
+	if depth > 0 {
+		parseerror.LogMessage(startLine, "Unterminated block comment.")
+	}
+}
+
+func (sc *Scanner) scanNumber() {
+	if sc.source[sc.start] == '0' {
+		switch sc.peek() {
+		case 'x', 'X':
+			sc.scanRadixNumber(16, "hexadecimal", isHexDigit)
+			return
+		case 'b', 'B':
+			sc.scanRadixNumber(2, "binary", isBinaryDigit)
+			return
+		case 'o', 'O':
+			sc.scanRadixNumber(8, "octal", isOctalDigit)
+			return
+		}
+	}
+
+	valid := sc.digits(sc.isDigit)
+
 	// look for a fractional part
+	fraction := false
 	if sc.peek() == '.' && sc.isDigit(sc.peekNext()) {
+		fraction = true
 		sc.advance() // consume "."
-		for sc.isDigit(sc.peek()) {
-			sc.advance()
+		valid = sc.digits(sc.isDigit) && valid
+	}
+
+	// look for an exponent
+	if sc.peek() == 'e' || sc.peek() == 'E' {
+		if sc.isDigit(sc.peekNext()) || ((sc.peekNext() == '+' || sc.peekNext() == '-') && sc.isDigit(sc.peekAt(2))) {
+			fraction = true
+			sc.advance() // consume "e"
+			sc.match('+')
+			sc.match('-')
+			valid = sc.digits(sc.isDigit) && valid
 		}
 	}
 
-	number, err := strconv.ParseFloat(sc.source[sc.start:sc.current], 64)
+	literal := sc.source[sc.start:sc.current]
+	if !valid {
+		sc.skipSuffix()
+		parseerror.LogMessage(sc.line, fmt.Sprintf("Invalid digit separator in number literal: %s", literal))
+		return
+	}
+
+	text := strings.ReplaceAll(literal, "_", "")
+	if sc.isSuffix('n') {
+		// bigint literals are *big.Int
+		sc.advance()
+		if fraction {
+			parseerror.LogMessage(sc.line, fmt.Sprintf("BigInt literal must be an integer: %sn", literal))
+			return
+		}
+		number, _ := new(big.Int).SetString(text, 10)
+		sc.addTokenWithLiteral(token.NUMBER, number)
+		return
+	}
+	if sc.isSuffix('d') {
+		// decimal literals are *big.Rat
+		sc.advance()
+		number, _ := new(big.Rat).SetString(text)
+		sc.addTokenWithLiteral(token.NUMBER, number)
+		return
+	}
+
+	if !fraction {
+		// integer literals are int64; larger ones are promoted to float64
+		if number, err := strconv.ParseInt(text, 10, 64); err == nil {
+			sc.addTokenWithLiteral(token.NUMBER, number)
+			return
+		}
+	}
+
+	number, err := strconv.ParseFloat(text, 64)
 	if err != nil {
-		panic("Invalid number format")
+		parseerror.LogMessage(sc.line, fmt.Sprintf("Number literal out of range: %s", literal))
 	} else {
 		sc.addTokenWithLiteral(token.NUMBER, number)
 	}
 }
 
+// scanRadixNumber scans the rest of a 0x, 0b or 0o integer literal. The
+// literal is an int64, or a bigint with the 'n' suffix.
+func (sc *Scanner) scanRadixNumber(base int, name string, isDigit func(byte) bool) {
+	sc.advance() // consume the base prefix
+	valid := isDigit(sc.peek()) && sc.digits(isDigit)
+	if sc.isAlphaNumeric(sc.peek()) && !sc.isSuffix('n') {
+		valid = false
+	}
+	if !valid {
+		sc.skipSuffix()
+		parseerror.LogMessage(sc.line, fmt.Sprintf("Invalid %s literal: %s", name, sc.source[sc.start:sc.current]))
+		return
+	}
+
+	literal := sc.source[sc.start:sc.current]
+	digits := strings.ReplaceAll(literal[2:], "_", "")
+	if sc.isSuffix('n') {
+		sc.advance()
+		number, _ := new(big.Int).SetString(digits, base)
+		sc.addTokenWithLiteral(token.NUMBER, number)
+		return
+	}
+	number, err := strconv.ParseInt(digits, base, 64)
+	if err != nil {
+		parseerror.LogMessage(sc.line, fmt.Sprintf("Integer literal out of range: %s", literal))
+	} else {
+		sc.addTokenWithLiteral(token.NUMBER, number)
+	}
+}
+
+// digits consumes a run of digits separated by single underscores. It
+// reports false if an underscore is not followed by a digit.
+func (sc *Scanner) digits(isDigit func(byte) bool) bool {
+	valid := true
+	for isDigit(sc.peek()) || sc.peek() == '_' {
+		if sc.peek() == '_' && !isDigit(sc.peekNext()) {
+			valid = false
+		}
+		sc.advance()
+	}
+	return valid
+}
+
+// skipSuffix consumes the rest of an invalid number literal, so that it is
+// reported once
+func (sc *Scanner) skipSuffix() {
+	for sc.isAlphaNumeric(sc.peek()) {
+		sc.advance()
+	}
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isBinaryDigit(c byte) bool {
+	return c == '0' || c == '1'
+}
+
+func isOctalDigit(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// isSuffix reports whether the next character is the number suffix c, and
+// not the start of an identifier
+func (sc *Scanner) isSuffix(c byte) bool {
+	return sc.peek() == c && !sc.isAlphaNumeric(sc.peekNext())
+}
+
 func (sc *Scanner) scanIdentifier() {
 	for sc.isAlphaNumeric(sc.peek()) {
 		sc.advance()
@@ -139,23 +411,74 @@ func (sc *Scanner) scanToken() {
 		sc.addToken(token.LEFTBRACE)
 	case '}':
 		sc.addToken(token.RIGHTBRACE)
+	case '[':
+		sc.addToken(token.LEFTBRACKET)
+	case ']':
+		sc.addToken(token.RIGHTBRACKET)
 	case ',':
 		sc.addToken(token.COMMA)
 	case '.':
-		sc.addToken(token.DOT)
+		if sc.peek() == '.' && sc.peekNext() == '.' {
+			sc.advance()
+			sc.advance()
+			sc.addToken(token.ELLIPSIS)
+		} else if sc.isDigit(sc.peek()) {
+			sc.digits(sc.isDigit)
+			parseerror.LogMessage(sc.line, fmt.Sprintf("Number literal cannot start with '.': %s", sc.source[sc.start:sc.current]))
+		} else {
+			sc.addToken(token.DOT)
+		}
 	case '-':
-		sc.addToken(token.MINUS)
+		if sc.match('-') {
+			sc.addToken(token.MINUSMINUS)
+		} else if sc.match('=') {
+			sc.addToken(token.MINUSEQUAL)
+		} else {
+			sc.addToken(token.MINUS)
+		}
 	case '+':
-		sc.addToken(token.PLUS)
+		if sc.match('+') {
+			sc.addToken(token.PLUSPLUS)
+		} else if sc.match('=') {
+			sc.addToken(token.PLUSEQUAL)
+		} else {
+			sc.addToken(token.PLUS)
+		}
 	case '?':
-		sc.addToken(token.QMARK)
+		if sc.match('.') {
+			sc.addToken(token.QMARKDOT)
+		} else if sc.match('?') {
+			sc.addToken(token.QMARKQMARK)
+		} else {
+			sc.addToken(token.QMARK)
+		}
 	case ':':
 		sc.addToken(token.COLON)
 	case ';':
 		sc.addToken(token.SEMICOLON)
+	case '%':
+		if sc.match('=') {
+			sc.addToken(token.PERCENTEQUAL)
+		} else {
+			sc.addToken(token.PERCENT)
+		}
+	case '&':
+		sc.addToken(token.AMPERSAND)
+	case '|':
+		sc.addToken(token.PIPE)
+	case '^':
+		sc.addToken(token.CARET)
+	case '~':
+		sc.addToken(token.TILDE)
 	case '*':
 		if sc.match('*') {
-			sc.addToken(token.POWER)
+			if sc.match('=') {
+				sc.addToken(token.POWEREQUAL)
+			} else {
+				sc.addToken(token.POWER)
+			}
+		} else if sc.match('=') {
+			sc.addToken(token.STAREQUAL)
 		} else {
 			sc.addToken(token.STAR)
 		}
@@ -168,18 +491,24 @@ func (sc *Scanner) scanToken() {
 	case '=':
 		if sc.match('=') {
 			sc.addToken(token.EQUALEQUAL)
+		} else if sc.match('>') {
+			sc.addToken(token.ARROW)
 		} else {
 			sc.addToken(token.EQUAL)
 		}
 	case '<':
 		if sc.match('=') {
 			sc.addToken(token.LESSEQUAL)
+		} else if sc.match('<') {
+			sc.addToken(token.LESSLESS)
 		} else {
 			sc.addToken(token.LESS)
 		}
 	case '>':
 		if sc.match('=') {
 			sc.addToken(token.GREATEREQUAL)
+		} else if sc.match('>') {
+			sc.addToken(token.GREATERGREATER)
 		} else {
 			sc.addToken(token.GREATER)
 		}
@@ -189,6 +518,10 @@ func (sc *Scanner) scanToken() {
 			for sc.peek() != '\n' && !sc.isAtEnd() {
 				sc.advance()
 			}
+		} else if sc.match('*') {
+			sc.scanBlockComment()
+		} else if sc.match('=') {
+			sc.addToken(token.SLASHEQUAL)
 		} else {
 			sc.addToken(token.SLASH)
 		}
@@ -197,9 +530,12 @@ func (sc *Scanner) scanToken() {
 	case ' ', '\r', '\t':
 		// do nothing
 	case '"':
-		sc.scanString()
+		sc.scanString(false)
 	default:
-		if sc.isDigit(c) {
+		if c == 'r' && sc.peek() == '"' {
+			sc.advance() // consume the opening "
+			sc.scanString(true)
+		} else if sc.isDigit(c) {
 			sc.scanNumber()
 		} else if sc.isAlpha(c) {
 			sc.scanIdentifier()
@@ -252,8 +588,13 @@ func (sc *Scanner) peek() byte {
 }
 
 func (sc *Scanner) peekNext() byte {
-	if sc.current+1 >= len(sc.source) {
+	return sc.peekAt(1)
+}
+
+// peekAt returns the character offset positions after the current one
+func (sc *Scanner) peekAt(offset int) byte {
+	if sc.current+offset >= len(sc.source) {
 		return 0
 	}
-	return sc.source[sc.current+1]
+	return sc.source[sc.current+offset]
 }
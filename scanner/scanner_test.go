@@ -1,12 +1,16 @@
 package scanner
 
 import (
+	"github.com/jfourkiotis/golox/parseerror"
 	"github.com/jfourkiotis/golox/token"
+	"math/big"
+	"os"
+	"strings"
 	"testing"
 )
 
 func TestScanTokens(t *testing.T) {
-	input := `48( ){     }, .-      +; * =	!=><<=>===!/
+	input := `48( ){     }, .-      +; * =	!=>< <=>===!/
 				// a comment
 			"some string"    
 		=
@@ -27,6 +31,11 @@ func TestScanTokens(t *testing.T) {
 
 							** *** ?:
 							: ?
+		% & | ^ ~ << >> <<< >>=
+		+= -= *= /= %= **= ++ -- +++ - -
+		[]
+		... .. .
+		?. ?? ??? ? . =>
 	`
 	tests := []struct {
 		expectedType   token.Type
@@ -80,6 +89,42 @@ func TestScanTokens(t *testing.T) {
 		{token.COLON, ":"},
 		{token.COLON, ":"},
 		{token.QMARK, "?"},
+		{token.PERCENT, "%"},
+		{token.AMPERSAND, "&"},
+		{token.PIPE, "|"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.LESSLESS, "<<"},
+		{token.GREATERGREATER, ">>"},
+		{token.LESSLESS, "<<"},
+		{token.LESS, "<"},
+		{token.GREATERGREATER, ">>"},
+		{token.EQUAL, "="},
+		{token.PLUSEQUAL, "+="},
+		{token.MINUSEQUAL, "-="},
+		{token.STAREQUAL, "*="},
+		{token.SLASHEQUAL, "/="},
+		{token.PERCENTEQUAL, "%="},
+		{token.POWEREQUAL, "**="},
+		{token.PLUSPLUS, "++"},
+		{token.MINUSMINUS, "--"},
+		{token.PLUSPLUS, "++"},
+		{token.PLUS, "+"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.LEFTBRACKET, "["},
+		{token.RIGHTBRACKET, "]"},
+		{token.ELLIPSIS, "..."},
+		{token.DOT, "."},
+		{token.DOT, "."},
+		{token.DOT, "."},
+		{token.QMARKDOT, "?."},
+		{token.QMARKQMARK, "??"},
+		{token.QMARKQMARK, "??"},
+		{token.QMARK, "?"},
+		{token.QMARK, "?"},
+		{token.DOT, "."},
+		{token.ARROW, "=>"},
 	}
 
 	scanner := New(input)
@@ -103,3 +148,167 @@ func TestScanTokens(t *testing.T) {
 	}
 
 }
+
+func TestBlockComments(t *testing.T) {
+	input := `a /* one line */ b
+	/* two
+	   lines */ c /* outer /* inner
+	*/ still a comment */ d
+	/**/ e /*/ not closed by the slash above */ f`
+
+	tests := []struct {
+		expectedLexeme string
+		expectedLine   int
+	}{
+		{"a", 1},
+		{"b", 1},
+		{"c", 3},
+		{"d", 4},
+		{"e", 5},
+		{"f", 5},
+	}
+
+	scanner := New(input)
+	tokens := scanner.ScanTokens()
+
+	if len(tests) != len(tokens)-1 {
+		t.Fatalf("tests - number of token is wrong. expected=%d, got=%d", len(tests), len(tokens)-1)
+	}
+
+	for i, test := range tests {
+		if test.expectedLexeme != tokens[i].Lexeme {
+			t.Fatalf("tests[%d] - token literal is wrong. expected=%q, got=%q", i, test.expectedLexeme, tokens[i].Lexeme)
+		}
+		if test.expectedLine != tokens[i].Line {
+			t.Fatalf("tests[%d] - token line is wrong. expected=%d, got=%d", i, test.expectedLine, tokens[i].Line)
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	output := &strings.Builder{}
+	parseerror.Output = output
+	defer func() { parseerror.HadError, parseerror.Output = false, os.Stderr }()
+
+	scanner := New("a\n/* outer /* inner */\n\n")
+	tokens := scanner.ScanTokens()
+
+	if !parseerror.HadError {
+		t.Fatalf("Expected an unterminated comment error")
+	}
+	// the error is reported where the comment starts, not at the end of the input
+	if expected := "[line 2] Error: : Unterminated block comment.\n"; output.String() != expected {
+		t.Errorf("Expected %q. Got=%q", expected, output.String())
+	}
+	if len(tokens) != 2 || tokens[1].Type != token.EOF {
+		t.Fatalf("Expected the comment to extend to the end of the input. Got=%v", tokens)
+	}
+}
+
+func TestScanNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0xFF", int64(255)},
+		{"0Xff", int64(255)},
+		{"0b1010", int64(10)},
+		{"0o17", int64(15)},
+		{"0x7FFF_FFFF_FFFF_FFFF", int64(9223372036854775807)},
+		{"1_000_000", int64(1000000)},
+		{"1e-9", 1e-9},
+		{"2.5E3", 2500.0},
+		{"1e+2", 100.0},
+		{"1_0.0_1", 10.01},
+		{"0", int64(0)},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"9223372036854775808", 9223372036854775808.0},
+		{"100000000000000000000000", 1e23},
+	}
+
+	for _, test := range tests {
+		scanner := New(test.input)
+		tokens := scanner.ScanTokens()
+		if len(tokens) != 2 || tokens[0].Type != token.NUMBER {
+			t.Fatalf("Expected a single number for %q. Got=%v", test.input, tokens)
+		}
+		if tokens[0].Literal != test.expected {
+			t.Errorf("Expected %v (%T) for %q. Got=%v (%T)", test.expected, test.expected, test.input, tokens[0].Literal, tokens[0].Literal)
+		}
+	}
+
+	scanner := New("0xFFFF_FFFF_FFFF_FFFFn")
+	tokens := scanner.ScanTokens()
+	if number, ok := tokens[0].Literal.(*big.Int); !ok || number.String() != "18446744073709551615" {
+		t.Errorf("Expected a bigint. Got=%v", tokens[0].Literal)
+	}
+}
+
+func TestScanInvalidNumberLiterals(t *testing.T) {
+	defer func() { parseerror.HadError = false }()
+
+	inputs := []string{"0x", "0xG1", "0b102", "0o8", "1__0", "1_", "0x_1", ".5", "1e999", "0xFFFFFFFFFFFFFFFF", "1.5n"}
+
+	for _, input := range inputs {
+		parseerror.HadError = false
+		scanner := New(input)
+		tokens := scanner.ScanTokens()
+		if !parseerror.HadError {
+			t.Errorf("Expected a parse error for %q", input)
+		}
+		for _, tok := range tokens {
+			if tok.Type == token.NUMBER {
+				t.Errorf("Unexpected number token for %q: %v", input, tok)
+			}
+		}
+	}
+}
+
+func TestScanStringLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"plain"`, "plain"},
+		{`"a\tb\nc"`, "a\tb\nc"},
+		{`"say \"hi\" \\ bye"`, `say "hi" \ bye`},
+		{`"\d+\w"`, `\d+\w`},
+		{`r"C:\new\path"`, `C:\new\path`},
+		{`r""`, ""},
+		{`""`, ""},
+		{`"""one line"""`, "one line"},
+		{"\"\"\"\n    SELECT *\n      FROM t\n    WHERE x = 1;\n    \"\"\"", "SELECT *\n  FROM t\nWHERE x = 1;"},
+		{"\"\"\"\n  a\n\n  b\\tc \\\"\"\"\n\"\"\"", "a\n\nb\tc \"\"\""},
+		{"r\"\"\"\n  <p class=\"x\">\\n</p>\n  \"\"\"", `<p class="x">\n</p>`},
+	}
+
+	for _, test := range tests {
+		scanner := New(test.input)
+		tokens := scanner.ScanTokens()
+		if len(tokens) != 2 || tokens[0].Type != token.STRING {
+			t.Fatalf("Expected a single string for %q. Got=%v", test.input, tokens)
+		}
+		if tokens[0].Literal != test.expected {
+			t.Errorf("Expected %q for %q. Got=%q", test.expected, test.input, tokens[0].Literal)
+		}
+	}
+
+	scanner := New("\"\"\"\na\nb\n\"\"\" x")
+	tokens := scanner.ScanTokens()
+	if tokens[1].Lexeme != "x" || tokens[1].Line != 4 {
+		t.Errorf("Expected 'x' on line 4. Got=%v", tokens[1])
+	}
+}
+
+func TestUnterminatedStrings(t *testing.T) {
+	defer func() { parseerror.HadError = false }()
+
+	for _, input := range []string{`"abc`, `"abc\"`, `r"abc`, "\"\"\"abc\n\"\"", `"""abc\"""`} {
+		parseerror.HadError = false
+		scanner := New(input)
+		scanner.ScanTokens()
+		if !parseerror.HadError {
+			t.Errorf("Expected an unterminated string error for %q", input)
+		}
+	}
+}
@@ -10,6 +10,9 @@ import (
 // Unused local variables found by variable resolution
 type Unused = map[ast.Stmt]bool // value is always false
 
+// Unreachable match cases, which follow a case that matches any value
+type Unreachable = map[ast.Stmt]bool
+
 // EnvSize keeps the environment size of each ast.Block & ast.Function
 // nodes
 type EnvSize = map[ast.Stmt]int
@@ -20,10 +23,11 @@ const (
 )
 
 type vInfo struct {
-	name   string
-	status int
-	isUsed bool
-	stmt   ast.Stmt
+	name    string
+	status  int
+	isUsed  bool
+	isConst bool
+	stmt    ast.Stmt
 }
 
 // rScope represents a Lox scope
@@ -40,18 +44,19 @@ func scopeLookup(name string, scope rScope) int {
 
 // Resolution keeps important information about local variables and functions
 type Resolution struct {
-	Unused Unused
+	Unused      Unused
+	Unreachable Unreachable // reported as warnings
 }
 
 // NewResolution creates an empty resolution object
 func NewResolution() Resolution {
-	return Resolution{Unused: make(Unused)}
+	return Resolution{Unused: make(Unused), Unreachable: make(Unreachable)}
 }
 
 // Resolve performs name resolution to the given statements
 func Resolve(statements []ast.Stmt) (Resolution, error) {
 	resolution := NewResolution()
-	resolver := &Resolver{scopes: make([]rScope, 0), currentFunction: ftNone, currentClass: ctNone}
+	resolver := &Resolver{scopes: make([]rScope, 0), currentFunction: ftNone, currentClass: ctNone, globalConsts: make(map[string]bool)}
 	err := resolver.resolveStatements(statements, resolution)
 	return resolution, err
 }
@@ -75,6 +80,8 @@ type Resolver struct {
 	scopes          []rScope
 	currentFunction int
 	currentClass    int
+	globalConsts    map[string]bool // constants declared at the top level
+	labels          []string        // the labels of the enclosing loops, "" if unlabeled
 }
 
 func (r *Resolver) resolve(node ast.Node, res Resolution) error {
@@ -93,12 +100,52 @@ func (r *Resolver) resolve(node ast.Node, res Resolution) error {
 			return nil
 		}
 		n.EnvIndex = index
+		if err := r.declareConst(n.Name, index, n.Const); err != nil {
+			return err
+		}
 		if n.Initializer != nil {
 			if err := r.resolve(n.Initializer, res); err != nil {
 				return err
 			}
 		}
 		r.define(n.Name, n)
+	case *ast.Destructure:
+		for _, binding := range n.Bindings {
+			index, err := r.declare(binding.Name, binding)
+			if err != nil {
+				return err
+			}
+			binding.EnvIndex = index
+			if err := r.declareConst(binding.Name, index, n.Const); err != nil {
+				return err
+			}
+		}
+		if err := r.resolve(n.Initializer, res); err != nil {
+			return err
+		}
+		for _, binding := range n.Bindings {
+			r.define(binding.Name, binding)
+		}
+	case *ast.ListLiteral:
+		for _, element := range n.Elements {
+			if err := r.resolve(element, res); err != nil {
+				return err
+			}
+		}
+	case *ast.DestructureAssign:
+		if err := r.resolve(n.Value, res); err != nil {
+			return err
+		}
+		for _, target := range n.Targets {
+			if variable, ok := target.(*ast.Variable); ok {
+				if err := r.checkAssignable(variable.Name); err != nil {
+					return err
+				}
+			}
+			if err := r.resolve(target, res); err != nil {
+				return err
+			}
+		}
 	case *ast.Variable:
 		if len(r.scopes) != 0 {
 			top := r.scopes[len(r.scopes)-1]
@@ -114,15 +161,33 @@ func (r *Resolver) resolve(node ast.Node, res Resolution) error {
 		if err := r.resolve(n.Value, res); err != nil {
 			return err
 		}
+		if err := r.checkAssignable(n.Name); err != nil {
+			return err
+		}
 		index, depth := r.resolveLocal(n, n.Name, res)
 		n.EnvIndex = index
 		n.EnvDepth = depth
+	case *ast.CompoundAssign:
+		if variable, ok := n.Target.(*ast.Variable); ok {
+			if err := r.checkAssignable(variable.Name); err != nil {
+				return err
+			}
+		}
+		if err := r.resolve(n.Target, res); err != nil {
+			return err
+		}
+		if err := r.resolve(n.Value, res); err != nil {
+			return err
+		}
 	case *ast.Function:
 		index, err := r.declare(n.Name, n)
 		if err != nil {
 			return err
 		}
 		n.EnvIndex = index
+		if err := r.declareConst(n.Name, index, false); err != nil {
+			return err
+		}
 		r.define(n.Name, n)
 		if err := r.resolveFunction(n, res, ftFunction); err != nil {
 			return err
@@ -159,7 +224,68 @@ func (r *Resolver) resolve(node ast.Node, res Resolution) error {
 				return err
 			}
 		}
+	case *ast.Yield:
+		if r.currentFunction == ftNone {
+			return semanticerror.Make("Cannot yield from top-level code.")
+		} else if r.currentFunction == ftInitializer {
+			return semanticerror.Make("Cannot yield from an initializer.")
+		}
+		if n.Value != nil {
+			if err := r.resolve(n.Value, res); err != nil {
+				return err
+			}
+		}
+		index, depth := r.resolveLocal(n, n.Keyword, res)
+		n.EnvIndex = index
+		n.EnvDepth = depth
+	case *ast.Match:
+		if err := r.resolve(n.Value, res); err != nil {
+			return err
+		}
+		catchAll := false
+		for _, matchCase := range n.Cases {
+			if catchAll {
+				res.Unreachable[matchCase] = true
+			}
+			if err := r.resolve(matchCase, res); err != nil {
+				return err
+			}
+			if matchCase.Guard == nil && matchesAnything(matchCase.Patterns) {
+				catchAll = true
+			}
+		}
+	case *ast.MatchCase:
+		r.pushScope()
+		defer r.popScope(n, res)
+		for _, pattern := range n.Patterns {
+			if err := r.resolvePattern(pattern, res); err != nil {
+				return err
+			}
+		}
+		if len(n.Patterns) > 1 && len(r.scopes[len(r.scopes)-1]) > 0 {
+			return semanticerror.MakeAt(n.Keyword, "Alternative patterns cannot bind variables.")
+		}
+		if n.Guard != nil {
+			if err := r.resolve(n.Guard, res); err != nil {
+				return err
+			}
+		}
+		if err := r.resolve(n.Body, res); err != nil {
+			return err
+		}
+	case *ast.Break:
+		if err := r.checkLabel(n.Label); err != nil {
+			return err
+		}
+	case *ast.Continue:
+		if err := r.checkLabel(n.Label); err != nil {
+			return err
+		}
 	case *ast.For:
+		if err := r.enterLoop(n.Label); err != nil {
+			return err
+		}
+		defer r.exitLoop()
 		if err := r.resolve(n.Increment, res); err != nil {
 			return err
 		}
@@ -172,13 +298,48 @@ func (r *Resolver) resolve(node ast.Node, res Resolution) error {
 		if err := r.resolve(n.Statement, res); err != nil {
 			return err
 		}
+	case *ast.ForIn:
+		if err := r.resolve(n.Iterable, res); err != nil {
+			return err
+		}
+		if err := r.enterLoop(n.Label); err != nil {
+			return err
+		}
+		defer r.exitLoop()
+		// each iteration binds the loop variable in a fresh environment
+		r.pushScope()
+		defer r.popScope(n, res)
+		index, err := r.declare(n.Name, nil)
+		if err != nil {
+			return err
+		}
+		n.EnvIndex = index
+		r.define(n.Name, nil)
+		if err := r.resolve(n.Statement, res); err != nil {
+			return err
+		}
 	case *ast.While:
+		if err := r.enterLoop(n.Label); err != nil {
+			return err
+		}
+		defer r.exitLoop()
 		if err := r.resolve(n.Condition, res); err != nil {
 			return err
 		}
 		if err := r.resolve(n.Statement, res); err != nil {
 			return err
 		}
+	case *ast.DoWhile:
+		if err := r.enterLoop(n.Label); err != nil {
+			return err
+		}
+		defer r.exitLoop()
+		if err := r.resolve(n.Statement, res); err != nil {
+			return err
+		}
+		if err := r.resolve(n.Condition, res); err != nil {
+			return err
+		}
 	case *ast.Binary:
 		if err := r.resolve(n.Left, res); err != nil {
 			return err
@@ -196,6 +357,14 @@ func (r *Resolver) resolve(node ast.Node, res Resolution) error {
 				return err
 			}
 		}
+	case *ast.Spread:
+		if err := r.resolve(n.Expression, res); err != nil {
+			return err
+		}
+	case *ast.NamedArgument:
+		if err := r.resolve(n.Value, res); err != nil {
+			return err
+		}
 	case *ast.Grouping:
 		if err := r.resolve(n.Expression, res); err != nil {
 			return err
@@ -226,6 +395,9 @@ func (r *Resolver) resolve(node ast.Node, res Resolution) error {
 			return err
 		}
 		n.EnvIndex = index
+		if err := r.declareConst(n.Name, index, false); err != nil {
+			return err
+		}
 		r.define(n.Name, n)
 
 		if n.SuperClass != nil {
@@ -272,6 +444,10 @@ func (r *Resolver) resolve(node ast.Node, res Resolution) error {
 		if err := r.resolve(n.Expression, res); err != nil {
 			return err
 		}
+	case *ast.OptionalChain:
+		if err := r.resolve(n.Expression, res); err != nil {
+			return err
+		}
 	case *ast.Set:
 		if err := r.resolve(n.Value, res); err != nil {
 			return err
@@ -310,12 +486,93 @@ func (r *Resolver) resolveStatements(statements []ast.Stmt, res Resolution) erro
 	return nil
 }
 
+// enterLoop makes a loop the target of the break and continue statements in
+// its body
+func (r *Resolver) enterLoop(label token.Token) error {
+	if label.Lexeme != "" {
+		for _, enclosing := range r.labels {
+			if enclosing == label.Lexeme {
+				return semanticerror.MakeAt(label, fmt.Sprintf("Label '%s' is already in use.", label.Lexeme))
+			}
+		}
+	}
+	r.labels = append(r.labels, label.Lexeme)
+	return nil
+}
+
+func (r *Resolver) exitLoop() {
+	r.labels = r.labels[:len(r.labels)-1]
+}
+
+// checkLabel reports a break or continue that targets a label of no
+// enclosing loop
+func (r *Resolver) checkLabel(label token.Token) error {
+	if label.Lexeme == "" {
+		return nil
+	}
+	for _, enclosing := range r.labels {
+		if enclosing == label.Lexeme {
+			return nil
+		}
+	}
+	return semanticerror.MakeAt(label, fmt.Sprintf("Undefined label '%s'.", label.Lexeme))
+}
+
+// resolvePattern declares the variables bound by a pattern in the current
+// scope
+func (r *Resolver) resolvePattern(pattern ast.Pattern, res Resolution) error {
+	switch n := pattern.(type) {
+	case *ast.LiteralPattern:
+		return r.resolve(n.Value, res)
+	case *ast.BindingPattern:
+		index, err := r.declare(n.Name, nil)
+		if err != nil {
+			return err
+		}
+		n.EnvIndex = index
+		r.define(n.Name, nil)
+	case *ast.ListPattern:
+		for _, element := range n.Elements {
+			if err := r.resolvePattern(element, res); err != nil {
+				return err
+			}
+		}
+		if n.Rest != nil {
+			return r.resolvePattern(n.Rest, res)
+		}
+	case *ast.ClassPattern:
+		if err := r.resolve(n.Class, res); err != nil {
+			return err
+		}
+		for _, field := range n.Fields {
+			if err := r.resolvePattern(field.Value, res); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesAnything is true if one of the patterns matches every value
+func matchesAnything(patterns []ast.Pattern) bool {
+	for _, pattern := range patterns {
+		switch pattern.(type) {
+		case *ast.WildcardPattern, *ast.BindingPattern:
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Resolver) resolveFunction(function *ast.Function, res Resolution, ftype int) error {
 	enclosingFunction := r.currentFunction
+	enclosingLabels := r.labels
 	r.currentFunction = ftype
+	r.labels = nil // break and continue cannot leave a function
 
 	resetCurrentFunction := func() {
 		r.currentFunction = enclosingFunction
+		r.labels = enclosingLabels
 	}
 
 	defer resetCurrentFunction()
@@ -324,13 +581,25 @@ func (r *Resolver) resolveFunction(function *ast.Function, res Resolution, ftype
 	defer r.popScope(function, res)
 
 	if !function.IsProperty() {
-		for _, param := range function.Params {
+		for i, param := range function.Params {
+			// a default value can refer to the parameters before it
+			if i < len(function.Defaults) && function.Defaults[i] != nil {
+				if err := r.resolve(function.Defaults[i], res); err != nil {
+					return err
+				}
+			}
 			if _, err := r.declare(param, nil); err != nil {
 				return err
 			}
 			r.define(param, nil)
 		}
 	}
+	if function.IsGenerator {
+		// the running generator lives in the slot after the parameters
+		top := r.scopes[len(r.scopes)-1]
+		top = append(top, vInfo{name: "yield", status: vDefined, isUsed: true})
+		r.scopes[len(r.scopes)-1] = top
+	}
 	return r.resolveStatements(function.Body, res)
 }
 
@@ -364,6 +633,10 @@ func (r *Resolver) popScope(stmt ast.Stmt, res Resolution) {
 		block.EnvSize = len(top)
 	} else if function, ok := stmt.(*ast.Function); ok {
 		function.EnvSize = len(top)
+	} else if forIn, ok := stmt.(*ast.ForIn); ok {
+		forIn.EnvSize = len(top)
+	} else if matchCase, ok := stmt.(*ast.MatchCase); ok {
+		matchCase.EnvSize = len(top)
 	}
 }
 
@@ -383,6 +656,39 @@ func (r *Resolver) declare(name token.Token, node ast.Node) (int, error) {
 	return -1, nil
 }
 
+// declareConst records whether the variable just declared at index is a
+// constant. A top-level constant cannot be redeclared.
+func (r *Resolver) declareConst(name token.Token, index int, isConst bool) error {
+	if len(r.scopes) != 0 {
+		r.scopes[len(r.scopes)-1][index].isConst = isConst
+		return nil
+	}
+	if r.globalConsts[name.Lexeme] {
+		return semanticerror.MakeAt(name, fmt.Sprintf("Cannot redeclare constant '%s'.", name.Lexeme))
+	}
+	if isConst {
+		r.globalConsts[name.Lexeme] = true
+	}
+	return nil
+}
+
+// checkAssignable reports an error if name resolves to a constant
+func (r *Resolver) checkAssignable(name token.Token) error {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		scope := r.scopes[i]
+		if index := scopeLookup(name.Lexeme, scope); index >= 0 {
+			if scope[index].isConst {
+				return semanticerror.MakeAt(name, fmt.Sprintf("Cannot assign to constant '%s'.", name.Lexeme))
+			}
+			return nil
+		}
+	}
+	if r.globalConsts[name.Lexeme] {
+		return semanticerror.MakeAt(name, fmt.Sprintf("Cannot assign to constant '%s'.", name.Lexeme))
+	}
+	return nil
+}
+
 func (r *Resolver) define(name token.Token, node ast.Node) {
 	if len(r.scopes) != 0 {
 		scope := r.scopes[len(r.scopes)-1]
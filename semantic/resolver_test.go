@@ -1,6 +1,7 @@
 package semantic
 
 import (
+	"github.com/jfourkiotis/golox/ast"
 	"github.com/jfourkiotis/golox/parser"
 	"github.com/jfourkiotis/golox/scanner"
 	"testing"
@@ -141,3 +142,237 @@ func TestResolveSuper(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveDestructuringUnused(t *testing.T) {
+	input := `
+	fun f() {
+		var [a, b, c] = [1, 2, 3];
+		var {x, y} = a;
+		return b + y;
+	}
+	`
+	s := scanner.New(input)
+	tokens := s.ScanTokens()
+	p := parser.New(tokens)
+	statements := p.Parse()
+
+	res, err := Resolve(statements)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	unused := make(map[string]bool)
+	for stmt := range res.Unused {
+		binding, ok := stmt.(*ast.Binding)
+		if !ok {
+			t.Fatalf("Expected *ast.Binding. Got=%T", stmt)
+		}
+		unused[binding.Name.Lexeme] = true
+	}
+	if len(unused) != 2 || !unused["c"] || !unused["x"] {
+		t.Errorf("Expected unused bindings c and x. Got=%v", unused)
+	}
+}
+
+func TestResolveDestructuringRedeclaration(t *testing.T) {
+	input := `
+	{
+		var [a, a] = [1, 2];
+		print a;
+	}
+	`
+	s := scanner.New(input)
+	tokens := s.ScanTokens()
+	p := parser.New(tokens)
+	statements := p.Parse()
+
+	_, err := Resolve(statements)
+	expected := "Variable 'a' already declared in this scope."
+	if err == nil {
+		t.Fatalf("Expected error.")
+	} else if err.Error() != expected {
+		t.Errorf("Expected error %q. Got %q", expected, err.Error())
+	}
+}
+
+func TestResolveConstAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+			const A = 1;
+			A = 2;
+			`,
+			"[line 3] Error at 'A': Cannot assign to constant 'A'.",
+		},
+		{
+			`
+			const A = 1;
+			fun f() {
+				fun g() {
+					A += 1;
+				}
+				return g;
+			}
+			`,
+			"[line 5] Error at 'A': Cannot assign to constant 'A'.",
+		},
+		{
+			`
+			fun f() {
+				const c = 1;
+				fun g() {
+					c++;
+				}
+				g();
+			}
+			`,
+			"[line 5] Error at 'c': Cannot assign to constant 'c'.",
+		},
+		{
+			`
+			{
+				const [a, b] = [1, 2];
+				var c = 3;
+				[c, b] = [a, c];
+			}
+			`,
+			"[line 5] Error at 'b': Cannot assign to constant 'b'.",
+		},
+		{
+			`
+			const A = 1;
+			var A = 2;
+			`,
+			"[line 3] Error at 'A': Cannot redeclare constant 'A'.",
+		},
+	}
+
+	for _, test := range tests {
+		s := scanner.New(test.input)
+		tokens := s.ScanTokens()
+		p := parser.New(tokens)
+		statements := p.Parse()
+
+		_, err := Resolve(statements)
+		if err == nil {
+			t.Errorf("Expected error %q", test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("Expected error %q. Got %q", test.expected, err.Error())
+		}
+	}
+}
+
+func TestResolveConstShadowing(t *testing.T) {
+	input := `
+	const A = 1;
+	fun f(A) {
+		A = 2;
+		return A;
+	}
+	{
+		var A = 3;
+		A = 4;
+		print A;
+	}
+	print f(0);
+	`
+	s := scanner.New(input)
+	tokens := s.ScanTokens()
+	p := parser.New(tokens)
+	statements := p.Parse()
+
+	if _, err := Resolve(statements); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestResolveYield(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"yield 1;", "Cannot yield from top-level code."},
+		{"class A { init() { yield 1; } }", "Cannot yield from an initializer."},
+	}
+
+	for _, test := range tests {
+		s := scanner.New(test.input)
+		tokens := s.ScanTokens()
+		p := parser.New(tokens)
+		statements := p.Parse()
+
+		_, err := Resolve(statements)
+		if err == nil {
+			t.Errorf("Expected error %q", test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("Expected error %q. Got %q", test.expected, err.Error())
+		}
+	}
+}
+
+func TestResolveMatch(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		unreachable int
+	}{
+		{`match (1) { case 1 => print 1; case _ => print 2; case 3 => print 3; case 4 => print 4; }`, "", 2},
+		{`match (1) { case x if x > 1 => print x; case x => print x; }`, "", 0},
+		{`match (1) { case [x, y] => print x + y; }`, "", 0},
+		{`match (1) { case [x, x] => print x; }`, "Variable 'x' already declared in this scope.", 0},
+		{`match (1) { case 1, x => print x; }`, "[line 1] Error at 'case': Alternative patterns cannot bind variables.", 0},
+	}
+
+	for _, test := range tests {
+		s := scanner.New(test.input)
+		tokens := s.ScanTokens()
+		p := parser.New(tokens)
+		statements := p.Parse()
+
+		resolution, err := Resolve(statements)
+		if test.expected == "" {
+			if err != nil {
+				t.Errorf("Unexpected error %v", err)
+			} else if len(resolution.Unreachable) != test.unreachable {
+				t.Errorf("Expected %d unreachable cases. Got %d", test.unreachable, len(resolution.Unreachable))
+			}
+		} else if err == nil {
+			t.Errorf("Expected error %q", test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("Expected error %q. Got %q", test.expected, err.Error())
+		}
+	}
+}
+
+func TestResolveLabels(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a: while (true) { b: while (true) { break a; } continue a; }", ""},
+		{"a: while (true) {} a: while (true) break a;", ""},
+		{"while (true) break nope;", "[line 1] Error at 'nope': Undefined label 'nope'."},
+		{"a: while (true) { a: do break a; while (true); }", "[line 1] Error at 'a': Label 'a' is already in use."},
+		{"l: while (true) { fun f() { while (true) continue l; } }", "[line 1] Error at 'l': Undefined label 'l'."},
+	}
+
+	for _, test := range tests {
+		s := scanner.New(test.input)
+		tokens := s.ScanTokens()
+		p := parser.New(tokens)
+		statements := p.Parse()
+
+		_, err := Resolve(statements)
+		if test.expected == "" {
+			if err != nil {
+				t.Errorf("Unexpected error %v", err)
+			}
+		} else if err == nil {
+			t.Errorf("Expected error %q", test.expected)
+		} else if err.Error() != test.expected {
+			t.Errorf("Expected error %q. Got %q", test.expected, err.Error())
+		}
+	}
+}
@@ -2,6 +2,7 @@ package semanticerror
 
 import (
 	"fmt"
+	"github.com/jfourkiotis/golox/token"
 	"os"
 )
 
@@ -16,5 +17,10 @@ func Make(message string) error {
 	return fmt.Errorf("%s", message)
 }
 
+// MakeAt creates a new semantic error that points at a token
+func MakeAt(tok token.Token, message string) error {
+	return fmt.Errorf("[line %v] Error at '%s': %s", tok.Line, tok.Lexeme, message)
+}
+
 // HadError is true if an evaluation error was encountered
 var HadError = false
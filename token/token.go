@@ -8,29 +8,50 @@ type Type string
 //
 const (
 	// single-character tokens
-	LEFTPAREN  = "("
-	RIGHTPAREN = ")"
-	LEFTBRACE  = "{"
-	RIGHTBRACE = "}"
-	COMMA      = ","
-	DOT        = "."
-	MINUS      = "-"
-	PLUS       = "+"
-	SEMICOLON  = ";"
-	SLASH      = "/"
-	STAR       = "*"
-	QMARK      = "?"
-	COLON      = ":"
+	LEFTPAREN    = "("
+	RIGHTPAREN   = ")"
+	LEFTBRACE    = "{"
+	RIGHTBRACE   = "}"
+	LEFTBRACKET  = "["
+	RIGHTBRACKET = "]"
+	COMMA        = ","
+	DOT          = "."
+	MINUS        = "-"
+	PLUS         = "+"
+	SEMICOLON    = ";"
+	SLASH        = "/"
+	STAR         = "*"
+	PERCENT      = "%"
+	AMPERSAND    = "&"
+	PIPE         = "|"
+	CARET        = "^"
+	TILDE        = "~"
+	QMARK        = "?"
+	COLON        = ":"
 	// one or two character tokens
-	BANG         = "!"
-	BANGEQUAL    = "!="
-	EQUAL        = "="
-	EQUALEQUAL   = "=="
-	GREATER      = ">"
-	GREATEREQUAL = ">="
-	LESS         = "<"
-	LESSEQUAL    = "<="
-	POWER        = "**"
+	BANG           = "!"
+	BANGEQUAL      = "!="
+	EQUAL          = "="
+	EQUALEQUAL     = "=="
+	GREATER        = ">"
+	GREATEREQUAL   = ">="
+	LESS           = "<"
+	LESSEQUAL      = "<="
+	POWER          = "**"
+	LESSLESS       = "<<"
+	GREATERGREATER = ">>"
+	PLUSEQUAL      = "+="
+	MINUSEQUAL     = "-="
+	STAREQUAL      = "*="
+	SLASHEQUAL     = "/="
+	PERCENTEQUAL   = "%="
+	POWEREQUAL     = "**="
+	PLUSPLUS       = "++"
+	MINUSMINUS     = "--"
+	ELLIPSIS       = "..."
+	ARROW          = "=>"
+	QMARKDOT       = "?."
+	QMARKQMARK     = "??"
 	// literals
 	IDENTIFIER = "IDENT"
 	STRING     = "STRING"
@@ -54,6 +75,12 @@ const (
 	WHILE    = "while"
 	BREAK    = "break"
 	CONTINUE = "continue"
+	CONST    = "const"
+	IN       = "in"
+	YIELD    = "yield"
+	MATCH    = "match"
+	CASE     = "case"
+	DO       = "do"
 	EOF      = "eof"
 	INVALID  = "__INVALID__"
 )